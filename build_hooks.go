@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/launch"
+)
+
+const (
+	HookStagePreBuild  = "pre-build"
+	HookStagePostBuild = "post-build"
+
+	HookPolicyFail = "fail" // the build fails if the hook fails
+	HookPolicyWarn = "warn" // a warning is logged if the hook fails
+
+	EnvBuildpackID        = "CNB_BUILDPACK_ID"
+	EnvBuildpackVersion   = "CNB_BUILDPACK_VERSION"
+	EnvBuildpackLayersDir = "CNB_BUILDPACK_LAYERS_DIR"
+)
+
+// buildHook is a platform executable run before or after each buildpack's bin/build.
+// Hooks are found in <platform>/hooks/<stage>.d, where hooks in an <escaped-buildpack-id> subdirectory only run for
+// that buildpack. A hook may declare its policy in <hook>.toml; the default policy is HookPolicyFail.
+type buildHook struct {
+	Path   string `toml:"-"`
+	Policy string `toml:"policy"`
+}
+
+// findHooks returns the hooks for the stage that apply to the buildpack: the hooks for every buildpack, followed by
+// those for the buildpack, each sorted by name.
+func findHooks(platformDir, stage, bpID string) ([]buildHook, error) {
+	stageDir := filepath.Join(platformDir, "hooks", stage+".d")
+	var hooks []buildHook
+	for _, dir := range []string{stageDir, filepath.Join(stageDir, launch.EscapeID(bpID))} {
+		fis, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			if fi.IsDir() || strings.HasSuffix(fi.Name(), ".toml") || !isExecutable(fi) {
+				continue
+			}
+			hook := buildHook{Path: filepath.Join(dir, fi.Name()), Policy: HookPolicyFail}
+			if _, err := toml.DecodeFile(hook.Path+".toml", &hook); err != nil && !os.IsNotExist(err) {
+				return nil, errors.Wrapf(err, "read policy of hook '%s'", hook.Path)
+			}
+			if hook.Policy != HookPolicyFail && hook.Policy != HookPolicyWarn {
+				return nil, errors.Errorf("hook '%s' has unknown policy '%s'", hook.Path, hook.Policy)
+			}
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, nil
+}
+
+func isExecutable(fi os.FileInfo) bool {
+	return runtime.GOOS == "windows" || fi.Mode()&0111 != 0
+}
+
+// hookEnv returns the env the buildpack's bin/build runs with.
+func hookEnv(bpTOML *buildpack.Descriptor, bpEnv BuildEnv, platformDir string) ([]string, error) {
+	if bpTOML.Buildpack.ClearEnv {
+		return bpEnv.List(), nil
+	}
+	return bpEnv.WithPlatform(platformDir)
+}
+
+// runHooks runs the hooks for the stage with the given env. Each hook receives the buildpack's layers directory and
+// the platform directory as arguments, and is subject to the buildpack's timeout.
+func (b *Builder) runHooks(stage string, hooks []buildHook, bp buildpack.GroupBuildpack, bpDir string, bpEnv []string, config buildpack.BuildConfig) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	bpLayersDir := filepath.Join(config.LayersDir, launch.EscapeID(bp.ID))
+	if err := os.MkdirAll(bpLayersDir, 0777); err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		b.Logger.Debugf("Running %s hook '%s' for buildpack %s", stage, hook.Path, bp)
+		cmd := exec.Command(hook.Path, bpLayersDir, config.PlatformDir) // #nosec G204
+		cmd.Dir = config.AppDir
+		cmd.Stdout = config.Out
+		cmd.Stderr = config.Err
+		cmd.Env = append(append([]string{}, bpEnv...),
+			buildpack.EnvBuildpackDir+"="+bpDir,
+			EnvBuildpackID+"="+bp.ID,
+			EnvBuildpackVersion+"="+bp.Version,
+			EnvBuildpackLayersDir+"="+bpLayersDir,
+		)
+		if err := buildpack.RunCmd(config.Context, cmd, config.Timeouts.For(bp.ID)); err != nil {
+			if config.Context != nil && config.Context.Err() != nil {
+				return buildpack.NewLifecycleError(err, buildpack.ErrTypeInterrupted)
+			}
+			if hook.Policy == HookPolicyWarn {
+				b.Logger.Warnf("%s hook '%s' failed for buildpack %s: %s", stage, hook.Path, bp, err)
+				continue
+			}
+			return errors.Wrapf(err, "%s hook '%s' failed for buildpack %s", stage, hook.Path, bp)
+		}
+	}
+	return nil
+}
@@ -1,17 +1,20 @@
 package lifecycle
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 
+	"github.com/pkg/errors"
+
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/env"
 	"github.com/buildpacks/lifecycle/launch"
-	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/platform"
 )
 
@@ -44,6 +47,16 @@ type Builder struct {
 	Out, Err       io.Writer
 	Logger         Logger
 	BuildpackStore BuildpackStore
+	Timeouts       buildpack.Timeouts
+	Context        context.Context            // when done, the running buildpack is killed and the build is aborted; may be nil
+	Report         *platform.BuildStatsReport // when set, the resource usage and layer sizes of each buildpack are recorded in the report
+	LogsDir        string                     // when set, the output of each buildpack is also written to a file in this directory
+	LogTail        int                        // the number of lines of output included in the error for a failed buildpack
+	CheckpointPath string                     // when set, the build is checkpointed here after each buildpack succeeds
+	Resume         bool                       // when set, the build continues from the checkpoint at CheckpointPath
+	Offline        bool                       // when set, buildpacks run without network access
+	EnvReport      *platform.BuildEnvReport   // when set, the modifications made to each buildpack's build env are recorded in the report
+	RootDirs       env.RootDirs               // extends the RootDirMap of the build env and, through the build metadata, of the launch env
 }
 
 func (b *Builder) Build() (*platform.BuildMetadata, error) {
@@ -54,13 +67,26 @@ func (b *Builder) Build() (*platform.BuildMetadata, error) {
 		return nil, err
 	}
 
-	processMap := newProcessMap()
-	plan := b.Plan
-	var bom []buildpack.BOMEntry
-	var slices []layers.Slice
-	var labels []buildpack.Label
+	state := buildState{plan: b.Plan, processMap: newProcessMap()}
+	if b.Resume {
+		if state, err = b.loadCheckpoint(); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.Report != nil {
+		b.Report.Offline = b.Offline
+		for _, bp := range b.Group.Group[:state.completed] {
+			b.Report.Buildpacks = append(b.Report.Buildpacks, resumedStats(bp, state.stats))
+		}
+	}
 
-	for _, bp := range b.Group.Group {
+	rootDirMap := env.ExtendRootDirMap(env.POSIXBuildEnv, b.RootDirs.Build)
+	for i, bp := range b.Group.Group {
+		if i < state.completed {
+			b.Logger.Infof("Skipping buildpack %s, completed before the checkpoint", bp)
+			continue
+		}
 		b.Logger.Debugf("Running build for buildpack %s", bp)
 
 		b.Logger.Debug("Looking up buildpack")
@@ -70,34 +96,87 @@ func (b *Builder) Build() (*platform.BuildMetadata, error) {
 		}
 
 		b.Logger.Debug("Finding plan")
-		bpPlan := plan.Find(bp.ID)
+		bpPlan := state.plan.Find(bp.ID)
 
 		b.Logger.Debug("Getting build environment")
-		bpEnv := env.NewBuildEnv(os.Environ(), b.Platform, bpTOML)
+		bpEnv := env.NewBuildEnv(os.Environ(), rootDirMap, b.Platform, bpTOML)
+		bpEnv.BuildpackID = bp.ID
+		var buildEnv buildpack.BuildEnv = bpEnv
+		var tracedEnv *execTracingEnv
+		if b.EnvReport != nil {
+			bpEnv.Trace = &env.Trace{Buildpack: bp.ID}
+			tracedEnv = &execTracingEnv{Env: bpEnv}
+			buildEnv = tracedEnv
+		}
+
+		b.Logger.Debug("Finding hooks")
+		preHooks, err := findHooks(config.PlatformDir, HookStagePreBuild, bp.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "find pre-build hooks")
+		}
+		postHooks, err := findHooks(config.PlatformDir, HookStagePostBuild, bp.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "find post-build hooks")
+		}
+		var bpDir string
+		var hookEnvList []string
+		if len(preHooks) > 0 || len(postHooks) > 0 {
+			bpDir = bpTOML.ConfigFile().Dir
+			if hookEnvList, err = hookEnv(bpTOML.ConfigFile(), bpEnv, config.PlatformDir); err != nil {
+				return nil, err
+			}
+		}
+		if err := b.runHooks(HookStagePreBuild, preHooks, bp, bpDir, hookEnvList, config); err != nil {
+			return nil, err
+		}
 
-		br, err := bpTOML.Build(bpPlan, config, bpEnv)
+		br, err := bpTOML.Build(bpPlan, config, buildEnv)
+		var stats platform.BuildpackStats
+		if b.Report != nil {
+			stats = b.buildpackStats(bp, br.Usage, err)
+			b.Report.Buildpacks = append(b.Report.Buildpacks, stats)
+		}
+		if b.EnvReport != nil {
+			b.EnvReport.Buildpacks = append(b.EnvReport.Buildpacks, platform.BuildpackEnv{ID: bp.ID, Version: bp.Version, Vars: tracedEnv.execVars})
+		}
 		if err != nil {
 			return nil, err
 		}
 
+		if err := b.runHooks(HookStagePostBuild, postHooks, bp, bpDir, hookEnvList, config); err != nil {
+			return nil, err
+		}
+
 		b.Logger.Debug("Updating buildpack processes")
 		updateDefaultProcesses(br.Processes, api.MustParse(bp.API), b.PlatformAPI)
 
-		bom = append(bom, br.BOM...)
-		labels = append(labels, br.Labels...)
-		plan = plan.Filter(br.MetRequires)
+		state.bom = append(state.bom, br.BOM...)
+		state.labels = append(state.labels, br.Labels...)
+		state.plan = state.plan.Filter(br.MetRequires)
 
 		b.Logger.Debug("Updating process list")
-		warning := processMap.add(br.Processes)
+		warning := state.processMap.add(br.Processes)
 		if warning != "" {
 			b.Logger.Warn(warning)
 		}
 
-		slices = append(slices, br.Slices...)
+		state.slices = append(state.slices, br.Slices...)
+		if b.Report != nil {
+			state.stats = append(state.stats, stats)
+		}
+		state.completed = i + 1
+
+		if b.CheckpointPath != "" {
+			b.Logger.Debug("Saving build checkpoint")
+			if err := b.saveCheckpoint(state); err != nil {
+				return nil, errors.Wrap(err, "save build checkpoint")
+			}
+		}
 
 		b.Logger.Debugf("Finished running build for buildpack %s", bp)
 	}
 
+	bom := state.bom
 	if b.PlatformAPI.Compare(api.MustParse("0.4")) < 0 { // PlatformAPI <= 0.3
 		config.Logger.Debug("Updating BOM entries")
 		for i := range bom {
@@ -105,20 +184,108 @@ func (b *Builder) Build() (*platform.BuildMetadata, error) {
 		}
 	}
 
+	if b.CheckpointPath != "" {
+		if err := b.removeCheckpoint(); err != nil {
+			return nil, errors.Wrap(err, "remove build checkpoint")
+		}
+	}
+
 	b.Logger.Debug("Listing processes")
-	procList := processMap.list()
+	procList := state.processMap.list()
 
 	b.Logger.Debug("Finished build")
 	return &platform.BuildMetadata{
 		BOM:                         bom,
 		Buildpacks:                  b.Group.Group,
-		Labels:                      labels,
+		Labels:                      state.labels,
 		Processes:                   procList,
-		Slices:                      slices,
-		BuildpackDefaultProcessType: processMap.defaultType,
+		Slices:                      state.slices,
+		BuildpackDefaultProcessType: state.processMap.defaultType,
+		RootDirs:                    b.RootDirs.Launch,
 	}, nil
 }
 
+func (b *Builder) buildpackStats(bp buildpack.GroupBuildpack, usage buildpack.Usage, buildErr error) platform.BuildpackStats {
+	stats := platform.BuildpackStats{
+		ID:          bp.ID,
+		Version:     bp.Version,
+		DurationMS:  usage.Duration.Milliseconds(),
+		UserCPUMS:   usage.UserTime.Milliseconds(),
+		SystemCPUMS: usage.SystemTime.Milliseconds(),
+		MaxRSSBytes: usage.MaxRSS,
+	}
+	if buildErr != nil {
+		stats.Error = buildErr.Error()
+	}
+	var err error
+	if stats.Layers, err = layerSizes(filepath.Join(b.LayersDir, launch.EscapeID(bp.ID))); err != nil {
+		b.Logger.Warnf("Failed to measure layers of buildpack %s: %s", bp, err)
+	}
+	return stats
+}
+
+// execTracingEnv is a traced build env that keeps the trace as it was when the env was last listed to run bin/build,
+// so that the build env report shows the env bin/build ran with and not the modifications its layers made after.
+type execTracingEnv struct {
+	*env.Env
+	execVars []env.VarTrace
+}
+
+func (e *execTracingEnv) WithPlatform(platformDir string) ([]string, error) {
+	environ, err := e.Env.WithPlatform(platformDir)
+	e.execVars = e.Trace.Vars()
+	return environ, err
+}
+
+func (e *execTracingEnv) List() []string {
+	e.execVars = e.Trace.Vars()
+	return e.Env.List()
+}
+
+// resumedStats returns the stats a checkpoint recorded for a buildpack that completed before it, marked as resumed.
+// Only the buildpack is known if the build was not reported when the checkpoint was made.
+func resumedStats(bp buildpack.GroupBuildpack, checkpointed []platform.BuildpackStats) platform.BuildpackStats {
+	stats := platform.BuildpackStats{ID: bp.ID, Version: bp.Version}
+	for _, s := range checkpointed {
+		if s.ID == bp.ID && s.Version == bp.Version {
+			stats = s
+			break
+		}
+	}
+	stats.Resumed = true
+	return stats
+}
+
+// layerSizes returns the total size of the regular files in each layer directory in bpLayersDir, sorted by name.
+func layerSizes(bpLayersDir string) ([]platform.LayerStats, error) {
+	fis, err := ioutil.ReadDir(bpLayersDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var out []platform.LayerStats
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		var size int64
+		if err := filepath.Walk(filepath.Join(bpLayersDir, fi.Name()), func(_ string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.Mode().IsRegular() {
+				size += fi.Size()
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		out = append(out, platform.LayerStats{Name: fi.Name(), SizeBytes: size})
+	}
+	return out, nil
+}
+
 // we set default = true for web processes when platformAPI >= 0.6 and buildpackAPI < 0.6
 func updateDefaultProcesses(processes []launch.Process, buildpackAPI *api.Version, platformAPI *api.Version) {
 	if platformAPI.Compare(api.MustParse("0.6")) < 0 || buildpackAPI.Compare(api.MustParse("0.6")) >= 0 {
@@ -153,6 +320,11 @@ func (b *Builder) BuildConfig() (buildpack.BuildConfig, error) {
 		Out:         b.Out,
 		Err:         b.Err,
 		Logger:      b.Logger,
+		Timeouts:    b.Timeouts,
+		Context:     b.Context,
+		LogsDir:     b.LogsDir,
+		LogTail:     b.LogTail,
+		Offline:     b.Offline,
 	}, nil
 }
 
@@ -0,0 +1,143 @@
+package lifecycle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/launch"
+	"github.com/buildpacks/lifecycle/layers"
+	"github.com/buildpacks/lifecycle/platform"
+)
+
+// buildCheckpoint is the state of a build after its first Completed buildpacks succeeded.
+// The layers those buildpacks created are already in the layers directory, so only their results are recorded.
+type buildCheckpoint struct {
+	GroupDigest        string                    `toml:"group-digest"`
+	PlanDigest         string                    `toml:"plan-digest"`
+	Completed          []string                  `toml:"completed"` // <id>@<version> of each completed buildpack, in group order
+	Plan               platform.BuildPlan        `toml:"plan"`      // the entries that are still unmet
+	BOM                []buildpack.BOMEntry      `toml:"bom"`
+	Labels             []buildpack.Label         `toml:"labels"`
+	Slices             []layers.Slice            `toml:"slices"`
+	Processes          []launch.Process          `toml:"processes"`
+	DefaultProcessType string                    `toml:"default-process-type"`
+	Stats              []platform.BuildpackStats `toml:"stats"` // of the completed buildpacks, if the build was reported
+}
+
+// buildState is everything the builder accumulates from the buildpacks that have run.
+type buildState struct {
+	completed  int
+	plan       platform.BuildPlan
+	bom        []buildpack.BOMEntry
+	labels     []buildpack.Label
+	slices     []layers.Slice
+	processMap processMap
+	stats      []platform.BuildpackStats
+}
+
+// saveCheckpoint records the build state, replacing the previous checkpoint.
+func (b *Builder) saveCheckpoint(state buildState) error {
+	cp, err := b.newCheckpoint()
+	if err != nil {
+		return err
+	}
+	for _, bp := range b.Group.Group[:state.completed] {
+		cp.Completed = append(cp.Completed, bp.String())
+	}
+	cp.Plan = state.plan
+	cp.BOM = state.bom
+	cp.Labels = state.labels
+	cp.Slices = state.slices
+	for _, proc := range state.processMap.typeToProcess {
+		cp.Processes = append(cp.Processes, proc)
+	}
+	sort.Slice(cp.Processes, func(i, j int) bool { return cp.Processes[i].Type < cp.Processes[j].Type })
+	cp.DefaultProcessType = state.processMap.defaultType
+	cp.Stats = state.stats
+
+	tmpPath := b.CheckpointPath + ".tmp"
+	if err := WriteTOML(tmpPath, cp); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, b.CheckpointPath)
+}
+
+// loadCheckpoint returns the build state recorded in the checkpoint, or the initial state if there is no checkpoint.
+// It fails if the checkpoint was made for a different group or plan.
+func (b *Builder) loadCheckpoint() (buildState, error) {
+	state := buildState{plan: b.Plan, processMap: newProcessMap()}
+	var cp buildCheckpoint
+	if _, err := toml.DecodeFile(b.CheckpointPath, &cp); os.IsNotExist(err) {
+		b.Logger.Infof("No build checkpoint found at '%s', building all buildpacks", b.CheckpointPath)
+		return state, nil
+	} else if err != nil {
+		return buildState{}, errors.Wrap(err, "read build checkpoint")
+	}
+
+	expected, err := b.newCheckpoint()
+	if err != nil {
+		return buildState{}, err
+	}
+	if cp.GroupDigest != expected.GroupDigest {
+		return buildState{}, errors.New("build checkpoint was made for a different buildpack group")
+	}
+	if cp.PlanDigest != expected.PlanDigest {
+		return buildState{}, errors.New("build checkpoint was made for a different build plan")
+	}
+	if len(cp.Completed) > len(b.Group.Group) {
+		return buildState{}, errors.New("build checkpoint has more completed buildpacks than the group")
+	}
+	for i, bp := range cp.Completed {
+		if bp != b.Group.Group[i].String() {
+			return buildState{}, errors.Errorf("build checkpoint completed '%s' where the group has '%s'", bp, b.Group.Group[i])
+		}
+	}
+
+	state.completed = len(cp.Completed)
+	state.plan = cp.Plan
+	state.bom = cp.BOM
+	state.labels = cp.Labels
+	state.slices = cp.Slices
+	for _, proc := range cp.Processes {
+		state.processMap.typeToProcess[proc.Type] = proc
+	}
+	state.processMap.defaultType = cp.DefaultProcessType
+	state.stats = cp.Stats
+	return state, nil
+}
+
+func (b *Builder) removeCheckpoint() error {
+	if err := os.Remove(b.CheckpointPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *Builder) newCheckpoint() (buildCheckpoint, error) {
+	groupDigest, err := tomlDigest(b.Group)
+	if err != nil {
+		return buildCheckpoint{}, errors.Wrap(err, "digest buildpack group")
+	}
+	planDigest, err := tomlDigest(b.Plan)
+	if err != nil {
+		return buildCheckpoint{}, errors.Wrap(err, "digest build plan")
+	}
+	return buildCheckpoint{GroupDigest: groupDigest, PlanDigest: planDigest}, nil
+}
+
+// tomlDigest returns the digest of the TOML encoding of v, which is stable because map keys are encoded in order.
+func tomlDigest(v interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(v); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
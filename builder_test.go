@@ -2,11 +2,15 @@ package lifecycle_test
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/memory"
@@ -19,6 +23,7 @@ import (
 	"github.com/buildpacks/lifecycle"
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/env"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 	"github.com/buildpacks/lifecycle/platform"
@@ -671,6 +676,503 @@ func testBuilder(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("a build report is requested", func() {
+			it.Before(func() {
+				builder.Report = &platform.BuildStatsReport{}
+			})
+
+			it("should record the usage and layer sizes of each buildpack", func() {
+				h.Mkdir(t, filepath.Join(layersDir, "A", "layer1", "bin"), filepath.Join(layersDir, "A", "layer2"))
+				h.Mkfile(t, "12345", filepath.Join(layersDir, "A", "layer1", "bin", "file"))
+				h.Mkfile(t, "123", filepath.Join(layersDir, "A", "layer1", "other"))
+				h.Mkfile(t, "[types]\nlaunch = true\n", filepath.Join(layersDir, "A", "layer1.toml"))
+
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().Build(gomock.Any(), config, gomock.Any()).Return(buildpack.BuildResult{
+					Usage: buildpack.Usage{
+						Duration:   1500 * time.Millisecond,
+						UserTime:   700 * time.Millisecond,
+						SystemTime: 200 * time.Millisecond,
+						MaxRSS:     4096,
+					},
+				}, nil)
+				bpB := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+				bpB.EXPECT().SupportsAssetPackages().Return(true)
+				bpB.EXPECT().Build(gomock.Any(), config, gomock.Any()).Return(buildpack.BuildResult{
+					Usage: buildpack.Usage{Duration: 20 * time.Millisecond},
+				}, nil)
+
+				if _, err := builder.Build(); err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+				h.AssertEq(t, builder.Report.Buildpacks, []platform.BuildpackStats{
+					{
+						ID:          "A",
+						Version:     "v1",
+						DurationMS:  1500,
+						UserCPUMS:   700,
+						SystemCPUMS: 200,
+						MaxRSSBytes: 4096,
+						Layers: []platform.LayerStats{
+							{Name: "layer1", SizeBytes: 8},
+							{Name: "layer2", SizeBytes: 0},
+						},
+					},
+					{ID: "B", Version: "v2", DurationMS: 20},
+				})
+			})
+
+			it("should record the buildpack that failed", func() {
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().Build(gomock.Any(), config, gomock.Any()).Return(buildpack.BuildResult{
+					Usage: buildpack.Usage{Duration: 3 * time.Second},
+				}, errors.New("some error"))
+
+				if _, err := builder.Build(); err == nil {
+					t.Fatal("Expected error.\n")
+				}
+				h.AssertEq(t, builder.Report.Buildpacks, []platform.BuildpackStats{
+					{ID: "A", Version: "v1", DurationMS: 3000, Error: "some error"},
+				})
+			})
+		})
+
+		when("a build env report is requested", func() {
+			it.Before(func() {
+				builder.EnvReport = &platform.BuildEnvReport{}
+			})
+
+			it("should record the modifications made to each buildpack's build env", func() {
+				layerDir := filepath.Join(layersDir, "A", "layer1")
+				h.Mkdir(t, filepath.Join(layerDir, "bin"))
+				otherLayerDir := filepath.Join(layersDir, "A", "layer2")
+				h.Mkdir(t, filepath.Join(otherLayerDir, "bin"))
+
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().Build(gomock.Any(), config, gomock.Any()).DoAndReturn(
+					func(_ buildpack.Plan, _ buildpack.BuildConfig, bpEnv buildpack.BuildEnv) (buildpack.BuildResult, error) {
+						h.AssertNil(t, bpEnv.AddRootDir(layerDir))
+						bpEnv.List() // bin/build runs with this env
+						return buildpack.BuildResult{}, bpEnv.AddRootDir(otherLayerDir)
+					})
+				bpB := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+				bpB.EXPECT().SupportsAssetPackages().Return(true)
+				bpB.EXPECT().Build(gomock.Any(), config, gomock.Any())
+
+				if _, err := builder.Build(); err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+				binDir := filepath.Join(layerDir, "bin")
+				h.AssertEq(t, builder.EnvReport.Buildpacks, []platform.BuildpackEnv{
+					{ID: "A", Version: "v1", Vars: []env.VarTrace{{Name: "PATH", Modifications: []env.Modification{
+						{Action: env.TraceActionInitial, Value: os.Getenv("PATH")},
+						{Action: env.TraceActionRootDir, Source: binDir, Layer: "layer1", Buildpack: "A", Value: binDir + string(os.PathListSeparator) + os.Getenv("PATH")},
+					}}}},
+					{ID: "B", Version: "v2"},
+				})
+			})
+
+			it("should record the env bin/build ran with", func() {
+				if runtime.GOOS == "windows" {
+					t.Skip("bin/build is a POSIX shell script")
+				}
+				h.Mkfile(t, "/platform/bin", filepath.Join(platformDir, "env", "PATH"))
+				bpDir := filepath.Join(tmpDir, "buildpacks", "A")
+				h.Mkdir(t, filepath.Join(bpDir, "bin"))
+				h.Mkfile(t, `#!/bin/sh
+printf %s "$PATH" > path-A
+mkdir -p "$1/layer1/bin"
+printf '[types]\nbuild = true\n' > "$1/layer1.toml"
+`, filepath.Join(bpDir, "bin", "build"))
+				h.AssertNil(t, os.Chmod(filepath.Join(bpDir, "bin", "build"), 0755))
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(&buildpack.Descriptor{
+					API:       api.Buildpack.Latest().String(),
+					Buildpack: buildpack.Info{ID: "A", Version: "v1"},
+					Dir:       bpDir,
+				}, nil)
+				bpB := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+				bpB.EXPECT().SupportsAssetPackages().Return(true)
+				bpB.EXPECT().Build(gomock.Any(), config, gomock.Any())
+
+				if _, err := builder.Build(); err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+				ranWith := h.Rdfile(t, filepath.Join(appDir, "path-A"))
+				h.AssertEq(t, ranWith, "/platform/bin"+string(os.PathListSeparator)+os.Getenv("PATH"))
+				h.AssertEq(t, builder.EnvReport.Buildpacks[0].Vars, []env.VarTrace{{Name: "PATH", Modifications: []env.Modification{
+					{Action: env.TraceActionInitial, Value: os.Getenv("PATH")},
+					{Action: env.TraceActionPlatform, Source: filepath.Join(platformDir, "env", "PATH"), Value: ranWith},
+				}}})
+			})
+		})
+
+		when("root dirs are configured", func() {
+			it.Before(func() {
+				builder.RootDirs = env.RootDirs{
+					Build:  map[string][]string{"lib/python3/site-packages": {"PYTHONPATH"}},
+					Launch: map[string][]string{"share/man": {"MANPATH"}},
+				}
+			})
+
+			it("should extend the build env and record the launch root dirs in the metadata", func() {
+				layerDir := filepath.Join(layersDir, "A", "layer1")
+				sitePackages := filepath.Join(layerDir, "lib", "python3", "site-packages")
+				h.Mkdir(t, sitePackages)
+
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().Build(gomock.Any(), config, gomock.Any()).DoAndReturn(
+					func(_ buildpack.Plan, _ buildpack.BuildConfig, bpEnv buildpack.BuildEnv) (buildpack.BuildResult, error) {
+						h.AssertNil(t, bpEnv.AddRootDir(layerDir))
+						h.AssertContains(t, bpEnv.List(), "PYTHONPATH="+sitePackages)
+						return buildpack.BuildResult{}, nil
+					})
+				bpB := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+				bpB.EXPECT().SupportsAssetPackages().Return(true)
+				bpB.EXPECT().Build(gomock.Any(), config, gomock.Any())
+
+				metadata, err := builder.Build()
+				if err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+				h.AssertEq(t, metadata.RootDirs, map[string][]string{"share/man": {"MANPATH"}})
+			})
+		})
+
+		when("the platform has hooks", func() {
+			var hooksDir, eventsPath string
+
+			it.Before(func() {
+				if runtime.GOOS == "windows" {
+					t.Skip("hook scripts are POSIX shell")
+				}
+				hooksDir = filepath.Join(platformDir, "hooks")
+				eventsPath = filepath.Join(tmpDir, "events")
+			})
+
+			writeHook := func(path, script string) {
+				t.Helper()
+				h.Mkdir(t, filepath.Dir(path))
+				h.Mkfile(t, "#!/bin/sh\n"+script+"\n", path)
+				h.AssertNil(t, os.Chmod(path, 0755))
+			}
+
+			expectBuild := func(id, version string, buildErr error) {
+				t.Helper()
+				bp := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup(id, version).Return(bp, nil)
+				bp.EXPECT().SupportsAssetPackages().Return(true)
+				bp.EXPECT().ConfigFile().Return(&buildpack.Descriptor{Dir: "/buildpacks/" + id}).AnyTimes()
+				bp.EXPECT().Build(gomock.Any(), config, gomock.Any()).DoAndReturn(
+					func(_ buildpack.Plan, _ buildpack.BuildConfig, _ buildpack.BuildEnv) (buildpack.BuildResult, error) {
+						f, err := os.OpenFile(eventsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+						h.AssertNil(t, err)
+						defer f.Close()
+						_, err = f.WriteString("build " + id + "\n")
+						h.AssertNil(t, err)
+						return buildpack.BuildResult{}, buildErr
+					})
+			}
+
+			it("should run the hooks for every buildpack and the hooks scoped to each buildpack around its build", func() {
+				record := `echo "$(basename "$0") $CNB_BUILDPACK_ID@$CNB_BUILDPACK_VERSION $1 $2 $CNB_BUILDPACK_LAYERS_DIR $CNB_BUILDPACK_DIR" >> ` + eventsPath
+				writeHook(filepath.Join(hooksDir, "pre-build.d", "10-pre"), record)
+				writeHook(filepath.Join(hooksDir, "post-build.d", "10-post"), record)
+				writeHook(filepath.Join(hooksDir, "post-build.d", "B", "20-post-b"), record)
+				h.Mkfile(t, "not a hook", filepath.Join(hooksDir, "post-build.d", "README"))
+				expectBuild("A", "v1", nil)
+				expectBuild("B", "v2", nil)
+
+				_, err := builder.Build()
+				h.AssertNil(t, err)
+
+				h.AssertEq(t, strings.Split(strings.TrimSpace(h.Rdfile(t, eventsPath)), "\n"), []string{
+					fmt.Sprintf("10-pre A@v1 %s %s %s /buildpacks/A", filepath.Join(layersDir, "A"), platformDir, filepath.Join(layersDir, "A")),
+					"build A",
+					fmt.Sprintf("10-post A@v1 %s %s %s /buildpacks/A", filepath.Join(layersDir, "A"), platformDir, filepath.Join(layersDir, "A")),
+					fmt.Sprintf("10-pre B@v2 %s %s %s /buildpacks/B", filepath.Join(layersDir, "B"), platformDir, filepath.Join(layersDir, "B")),
+					"build B",
+					fmt.Sprintf("10-post B@v2 %s %s %s /buildpacks/B", filepath.Join(layersDir, "B"), platformDir, filepath.Join(layersDir, "B")),
+					fmt.Sprintf("20-post-b B@v2 %s %s %s /buildpacks/B", filepath.Join(layersDir, "B"), platformDir, filepath.Join(layersDir, "B")),
+				})
+			})
+
+			it("should run hooks with the buildpack's build env", func() {
+				h.Mkfile(t, "some-value", filepath.Join(platformDir, "env", "SOME_PLATFORM_VAR"))
+				writeHook(filepath.Join(hooksDir, "pre-build.d", "A", "env"), `echo "$SOME_PLATFORM_VAR" >> `+eventsPath)
+				expectBuild("A", "v1", nil)
+				expectBuild("B", "v2", nil)
+
+				_, err := builder.Build()
+				h.AssertNil(t, err)
+				h.AssertEq(t, h.Rdfile(t, eventsPath), "some-value\nbuild A\nbuild B\n")
+			})
+
+			it("should not run post-build hooks for a buildpack that fails", func() {
+				writeHook(filepath.Join(hooksDir, "post-build.d", "post"), `echo post >> `+eventsPath)
+				expectBuild("A", "v1", errors.New("some error"))
+
+				_, err := builder.Build()
+				h.AssertError(t, err, "some error")
+				h.AssertEq(t, h.Rdfile(t, eventsPath), "build A\n")
+			})
+
+			it("should fail the build when a hook fails", func() {
+				writeHook(filepath.Join(hooksDir, "pre-build.d", "A", "check"), "exit 3")
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().ConfigFile().Return(&buildpack.Descriptor{}).AnyTimes()
+
+				_, err := builder.Build()
+				h.AssertError(t, err, fmt.Sprintf("pre-build hook '%s' failed for buildpack A@v1: exit status 3", filepath.Join(hooksDir, "pre-build.d", "A", "check")))
+			})
+
+			it("should warn when a hook with the warn policy fails", func() {
+				writeHook(filepath.Join(hooksDir, "post-build.d", "scan"), "exit 3")
+				h.Mkfile(t, `policy = "warn"`, filepath.Join(hooksDir, "post-build.d", "scan.toml"))
+				expectBuild("A", "v1", nil)
+				expectBuild("B", "v2", nil)
+
+				_, err := builder.Build()
+				h.AssertNil(t, err)
+				assertLogEntry(t, logHandler, "post-build hook '"+filepath.Join(hooksDir, "post-build.d", "scan")+"' failed for buildpack A@v1: exit status 3")
+			})
+
+			it("should kill a hook that runs longer than the buildpack's timeout", func() {
+				writeHook(filepath.Join(hooksDir, "pre-build.d", "A", "slow"), "sleep 10")
+				builder.Timeouts = buildpack.Timeouts{ByID: map[string]time.Duration{"A": 100 * time.Millisecond}}
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().ConfigFile().Return(&buildpack.Descriptor{}).AnyTimes()
+
+				_, err := builder.Build()
+				h.AssertError(t, err, "timed out after 100ms")
+			})
+
+			it("should abort the build when interrupted during a hook, regardless of its policy", func() {
+				writeHook(filepath.Join(hooksDir, "pre-build.d", "A", "slow"), "echo started >> "+eventsPath+"\nsleep 10")
+				h.Mkfile(t, `policy = "warn"`, filepath.Join(hooksDir, "pre-build.d", "A", "slow.toml"))
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				builder.Context = ctx
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().ConfigFile().Return(&buildpack.Descriptor{}).AnyTimes()
+				go func() {
+					for {
+						if _, err := os.Stat(eventsPath); err == nil {
+							cancel()
+							return
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+				}()
+
+				start := time.Now()
+				_, err := builder.Build()
+				if lerr, ok := err.(*buildpack.Error); !ok || lerr.Type != buildpack.ErrTypeInterrupted {
+					t.Fatalf("Expected an interrupted error, got: %v", err)
+				}
+				if time.Since(start) > 5*time.Second {
+					t.Fatal("Expected the hook to be killed")
+				}
+			})
+
+			it("should fail for an unknown policy", func() {
+				writeHook(filepath.Join(hooksDir, "post-build.d", "scan"), "true")
+				h.Mkfile(t, `policy = "ignore"`, filepath.Join(hooksDir, "post-build.d", "scan.toml"))
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+
+				_, err := builder.Build()
+				h.AssertError(t, err, "has unknown policy 'ignore'")
+			})
+		})
+
+		when("checkpointing", func() {
+			var checkpointPath string
+
+			it.Before(func() {
+				checkpointPath = filepath.Join(layersDir, "build-checkpoint.toml")
+				builder.CheckpointPath = checkpointPath
+				builder.Plan = platform.BuildPlan{
+					Entries: []platform.BuildPlanEntry{
+						{
+							Providers: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "B", Version: "v2"}},
+							Requires:  []buildpack.Require{{Name: "some-dep"}},
+						},
+						{
+							Providers: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "B", Version: "v2"}},
+							Requires:  []buildpack.Require{{Name: "some-unmet-dep"}},
+						},
+					},
+				}
+			})
+
+			failB := func() {
+				t.Helper()
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().Build(gomock.Any(), config, gomock.Any()).Return(buildpack.BuildResult{
+					BOM:         []buildpack.BOMEntry{{Require: buildpack.Require{Name: "dep-a"}, Buildpack: buildpack.GroupBuildpack{ID: "A", Version: "v1"}}},
+					Labels:      []buildpack.Label{{Key: "some-key", Value: "some-value"}},
+					MetRequires: []string{"some-dep"},
+					Processes:   []launch.Process{{Type: "web", Command: "a-cmd", Default: true, BuildpackID: "A"}},
+					Slices:      []layers.Slice{{Paths: []string{"a-path"}}},
+					Usage:       buildpack.Usage{Duration: 1500 * time.Millisecond},
+				}, nil)
+				bpB := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+				bpB.EXPECT().SupportsAssetPackages().Return(true)
+				bpB.EXPECT().Build(gomock.Any(), config, gomock.Any()).Return(buildpack.BuildResult{}, errors.New("some error"))
+
+				_, err := builder.Build()
+				h.AssertError(t, err, "some error")
+			}
+
+			it("should save a checkpoint after each buildpack that succeeds", func() {
+				failB()
+				h.AssertStringContains(t, h.Rdfile(t, checkpointPath), `completed = ["A@v1"]`)
+			})
+
+			it("should remove the checkpoint when the build succeeds", func() {
+				bpA := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+				bpA.EXPECT().SupportsAssetPackages().Return(true)
+				bpA.EXPECT().Build(gomock.Any(), config, gomock.Any())
+				bpB := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+				bpB.EXPECT().SupportsAssetPackages().Return(true)
+				bpB.EXPECT().Build(gomock.Any(), config, gomock.Any())
+
+				_, err := builder.Build()
+				h.AssertNil(t, err)
+				h.AssertPathDoesNotExist(t, checkpointPath)
+			})
+
+			when("resuming", func() {
+				it("should continue from the first incomplete buildpack", func() {
+					failB()
+
+					builder.Resume = true
+					bpB := testmock.NewMockBuildpack(mockCtrl)
+					buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+					bpB.EXPECT().SupportsAssetPackages().Return(true)
+					bpB.EXPECT().Build(
+						buildpack.Plan{Entries: []buildpack.Require{{Name: "some-unmet-dep"}}},
+						config,
+						gomock.Any(),
+					).Return(buildpack.BuildResult{
+						BOM:       []buildpack.BOMEntry{{Require: buildpack.Require{Name: "dep-b"}, Buildpack: buildpack.GroupBuildpack{ID: "B", Version: "v2"}}},
+						Processes: []launch.Process{{Type: "worker", Command: "b-cmd", BuildpackID: "B"}},
+					}, nil)
+
+					metadata, err := builder.Build()
+					h.AssertNil(t, err)
+					h.AssertEq(t, metadata.BOM, []buildpack.BOMEntry{
+						{Require: buildpack.Require{Name: "dep-a"}, Buildpack: buildpack.GroupBuildpack{ID: "A", Version: "v1"}},
+						{Require: buildpack.Require{Name: "dep-b"}, Buildpack: buildpack.GroupBuildpack{ID: "B", Version: "v2"}},
+					})
+					h.AssertEq(t, metadata.Labels, []buildpack.Label{{Key: "some-key", Value: "some-value"}})
+					h.AssertEq(t, metadata.Slices, []layers.Slice{{Paths: []string{"a-path"}}})
+					h.AssertEq(t, metadata.Processes, []launch.Process{
+						{Type: "web", Command: "a-cmd", BuildpackID: "A"},
+						{Type: "worker", Command: "b-cmd", BuildpackID: "B"},
+					})
+					h.AssertEq(t, metadata.BuildpackDefaultProcessType, "web")
+					h.AssertPathDoesNotExist(t, checkpointPath)
+				})
+
+				it("should report the buildpacks completed before the checkpoint as resumed", func() {
+					builder.Report = &platform.BuildStatsReport{}
+					failB()
+
+					builder.Resume = true
+					builder.Report = &platform.BuildStatsReport{}
+					bpB := testmock.NewMockBuildpack(mockCtrl)
+					buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+					bpB.EXPECT().SupportsAssetPackages().Return(true)
+					bpB.EXPECT().Build(gomock.Any(), config, gomock.Any()).Return(buildpack.BuildResult{
+						Usage: buildpack.Usage{Duration: 20 * time.Millisecond},
+					}, nil)
+
+					_, err := builder.Build()
+					h.AssertNil(t, err)
+					h.AssertEq(t, builder.Report.Buildpacks, []platform.BuildpackStats{
+						{ID: "A", Version: "v1", DurationMS: 1500, Resumed: true},
+						{ID: "B", Version: "v2", DurationMS: 20},
+					})
+				})
+
+				it("should report the completed buildpacks when the checkpoint has no stats", func() {
+					failB()
+
+					builder.Resume = true
+					builder.Report = &platform.BuildStatsReport{}
+					bpB := testmock.NewMockBuildpack(mockCtrl)
+					buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+					bpB.EXPECT().SupportsAssetPackages().Return(true)
+					bpB.EXPECT().Build(gomock.Any(), config, gomock.Any())
+
+					_, err := builder.Build()
+					h.AssertNil(t, err)
+					h.AssertEq(t, builder.Report.Buildpacks, []platform.BuildpackStats{
+						{ID: "A", Version: "v1", Resumed: true},
+						{ID: "B", Version: "v2"},
+					})
+				})
+
+				it("should build every buildpack when there is no checkpoint", func() {
+					builder.Resume = true
+					bpA := testmock.NewMockBuildpack(mockCtrl)
+					buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA, nil)
+					bpA.EXPECT().SupportsAssetPackages().Return(true)
+					bpA.EXPECT().Build(gomock.Any(), config, gomock.Any())
+					bpB := testmock.NewMockBuildpack(mockCtrl)
+					buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB, nil)
+					bpB.EXPECT().SupportsAssetPackages().Return(true)
+					bpB.EXPECT().Build(gomock.Any(), config, gomock.Any())
+
+					_, err := builder.Build()
+					h.AssertNil(t, err)
+				})
+
+				it("should fail if the group changed", func() {
+					failB()
+
+					builder.Resume = true
+					builder.Group.Group[1].Version = "v3"
+					_, err := builder.Build()
+					h.AssertError(t, err, "build checkpoint was made for a different buildpack group")
+				})
+
+				it("should fail if the plan changed", func() {
+					failB()
+
+					builder.Resume = true
+					builder.Plan.Entries = builder.Plan.Entries[1:]
+					_, err := builder.Build()
+					h.AssertError(t, err, "build checkpoint was made for a different build plan")
+				})
+			})
+		})
+
 		when("building fails", func() {
 			when("first buildpack build fails", func() {
 				it("should error", func() {
@@ -1,6 +1,7 @@
 package buildpack
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 
@@ -33,6 +35,11 @@ type BuildConfig struct {
 	Out         io.Writer
 	Err         io.Writer
 	Logger      Logger
+	Timeouts    Timeouts
+	Context     context.Context // when done, the running build process is killed; may be nil
+	LogsDir     string          // when set, the output of each buildpack is also written to <logs>/<escaped-id>.log
+	LogTail     int             // the number of lines of output to include in a build failure
+	Offline     bool            // when set, bin/build runs without network access (Linux only)
 }
 
 type BuildResult struct {
@@ -41,6 +48,15 @@ type BuildResult struct {
 	MetRequires []string
 	Processes   []launch.Process
 	Slices      []layers.Slice
+	Usage       Usage // also set when bin/build fails
+}
+
+// Usage describes the resources used by a buildpack's bin/build process.
+type Usage struct {
+	Duration   time.Duration
+	UserTime   time.Duration
+	SystemTime time.Duration
+	MaxRSS     int64 // in bytes; zero if unknown
 }
 
 func (bom *BOMEntry) ConvertMetadataToVersion() {
@@ -85,8 +101,9 @@ func (b *Descriptor) Build(bpPlan Plan, config BuildConfig, bpEnv BuildEnv) (Bui
 	}
 
 	config.Logger.Debug("Running build command")
-	if err := b.runBuildCmd(bpLayersDir, bpPlanPath, config, bpEnv); err != nil {
-		return BuildResult{}, err
+	usage, err := b.runBuildCmd(bpLayersDir, bpPlanPath, config, bpEnv)
+	if err != nil {
+		return BuildResult{Usage: usage}, err
 	}
 
 	config.Logger.Debug("Processing layers")
@@ -101,7 +118,9 @@ func (b *Descriptor) Build(bpPlan Plan, config BuildConfig, bpEnv BuildEnv) (Bui
 	}
 
 	config.Logger.Debug("Reading output files")
-	return b.readOutputFiles(bpLayersDir, bpPlanPath, bpPlan, config.Logger)
+	br, err := b.readOutputFiles(bpLayersDir, bpPlanPath, bpPlan, config.Logger)
+	br.Usage = usage
+	return br, err
 }
 
 func (b *Descriptor) SupportsAssetPackages() bool {
@@ -176,7 +195,7 @@ func WriteTOML(path string, data interface{}) error {
 	return toml.NewEncoder(f).Encode(data)
 }
 
-func (b *Descriptor) runBuildCmd(bpLayersDir, bpPlanPath string, config BuildConfig, bpEnv BuildEnv) error {
+func (b *Descriptor) runBuildCmd(bpLayersDir, bpPlanPath string, config BuildConfig, bpEnv BuildEnv) (Usage, error) {
 	cmd := exec.Command(
 		filepath.Join(b.Dir, "bin", "build"),
 		bpLayersDir,
@@ -184,24 +203,41 @@ func (b *Descriptor) runBuildCmd(bpLayersDir, bpPlanPath string, config BuildCon
 		bpPlanPath,
 	) // #nosec G204
 	cmd.Dir = config.AppDir
-	cmd.Stdout = config.Out
-	cmd.Stderr = config.Err
 
-	var err error
+	output, err := openBuildLog(config.LogsDir, b.Buildpack.ID, config.LogTail)
+	if err != nil {
+		return Usage{}, err
+	}
+	defer output.Close()
+	cmd.Stdout = output.tee(config.Out)
+	cmd.Stderr = output.tee(config.Err)
+
 	if b.Buildpack.ClearEnv {
 		cmd.Env = bpEnv.List()
 	} else {
 		cmd.Env, err = bpEnv.WithPlatform(config.PlatformDir)
 		if err != nil {
-			return err
+			return Usage{}, err
 		}
 	}
 	cmd.Env = append(cmd.Env, EnvBuildpackDir+"="+b.Dir)
+	if config.Offline {
+		if err := isolateNetwork(cmd); err != nil {
+			return Usage{}, err
+		}
+	}
 
-	if err := cmd.Run(); err != nil {
-		return NewLifecycleError(err, ErrTypeBuildpack)
+	start := time.Now()
+	err = RunCmd(config.Context, cmd, config.Timeouts.For(b.Buildpack.ID))
+	usage := processUsage(cmd.ProcessState)
+	usage.Duration = time.Since(start)
+	if err != nil {
+		if interrupted(config.Context) {
+			return usage, NewLifecycleError(err, ErrTypeInterrupted)
+		}
+		return usage, NewLifecycleError(output.failure(b.Buildpack.ID, b.Buildpack.Version, err), ErrTypeBuildpack)
 	}
-	return nil
+	return usage, nil
 }
 
 func (b *Descriptor) setupEnv(pathToLayerMetadataFile map[string]layertypes.LayerMetadataFile, buildEnv BuildEnv) error {
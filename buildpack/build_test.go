@@ -2,19 +2,24 @@ package buildpack_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/memory"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
@@ -36,6 +41,8 @@ func TestBuild(t *testing.T) {
 //go:generate mockgen -package testmock -destination testmock/env.go github.com/buildpacks/lifecycle BuildEnv
 
 func testBuild(t *testing.T, when spec.G, it spec.S) {
+	ignoreUsage := cmpopts.IgnoreFields(buildpack.BuildResult{}, "Usage")
+
 	var (
 		bpTOML         buildpack.Descriptor
 		mockCtrl       *gomock.Controller
@@ -179,6 +186,19 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 				}
 			})
 
+			it("should report the resource usage of bin/build", func() {
+				br, err := bpTOML.Build(buildpack.Plan{}, config, mockEnv)
+				if err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+				if br.Usage.Duration <= 0 {
+					t.Fatalf("Expected a duration, got %s", br.Usage.Duration)
+				}
+				if runtime.GOOS != "windows" && br.Usage.MaxRSS <= 0 {
+					t.Fatalf("Expected max RSS, got %d", br.Usage.MaxRSS)
+				}
+			})
+
 			when("build result", func() {
 				it("should get bom entries from launch.toml and unmet requires from build.toml", func() {
 					bpPlan := buildpack.Plan{
@@ -256,7 +276,7 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 						MetRequires: []string{"some-deprecated-bp-replace-version-dep", "some-dep", "some-replace-version-dep"},
 						Processes:   []launch.Process{},
 						Slices:      []layers.Slice{},
-					}); s != "" {
+					}, ignoreUsage); s != "" {
 						t.Fatalf("Unexpected:\n%s\n", s)
 					}
 				})
@@ -286,7 +306,7 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 						MetRequires: nil,
 						Processes:   []launch.Process{},
 						Slices:      []layers.Slice{},
-					}); s != "" {
+					}, ignoreUsage); s != "" {
 						t.Fatalf("Unexpected:\n%s\n", s)
 					}
 				})
@@ -317,7 +337,7 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 								{Type: "web", Command: "other-cmd", BuildpackID: "A", Default: false},
 							},
 							Slices: []layers.Slice{},
-						}); s != "" {
+						}, ignoreUsage); s != "" {
 							t.Fatalf("Unexpected metadata:\n%s\n", s)
 						}
 					})
@@ -341,7 +361,7 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 						MetRequires: nil,
 						Processes:   []launch.Process{},
 						Slices:      []layers.Slice{{Paths: []string{"some-path", "some-other-path"}}},
-					}); s != "" {
+					}, ignoreUsage); s != "" {
 						t.Fatalf("Unexpected:\n%s\n", s)
 					}
 				})
@@ -416,6 +436,35 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("offline", func() {
+			it.Before(func() {
+				if runtime.GOOS != "linux" {
+					t.Skip("network isolation is only supported on Linux")
+				}
+				bpTOML.Dir = filepath.Join(tmpDir, "offline-bp")
+				h.Mkdir(t, filepath.Join(bpTOML.Dir, "bin"))
+				h.Mkfile(t, "#!/bin/bash\n"+
+					"tail -n +3 /proc/net/dev | cut -d: -f1 | tr -d ' '\n"+
+					"echo \"uid=$(id -u)\"\n"+
+					"(exec 3<>/dev/tcp/127.0.0.1/9) 2>&1 || true\n",
+					filepath.Join(bpTOML.Dir, "bin", "build"),
+				)
+				h.AssertNil(t, os.Chmod(filepath.Join(bpTOML.Dir, "bin", "build"), 0755))
+				config.Offline = true
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(os.Environ(), nil)
+			})
+
+			it("should run bin/build as the same user with only a loopback interface that is up", func() {
+				if _, err := bpTOML.Build(buildpack.Plan{}, config, mockEnv); err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+				lines := strings.Split(strings.TrimSpace(h.CleanEndings(stdout.String())), "\n")
+				h.AssertEq(t, lines[0], "lo")
+				h.AssertEq(t, lines[1], "uid="+strconv.Itoa(os.Getuid()))
+				h.AssertStringContains(t, lines[2], "Connection refused")
+			})
+		})
+
 		when("building fails", func() {
 			it("should error when layer directories cannot be created", func() {
 				h.Mkfile(t, "some-data", filepath.Join(layersDir, "A"))
@@ -460,6 +509,70 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 				}
 			})
 
+			it("should name the buildpack and include the end of its output in the error", func() {
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), "TEST_ENV=Av1"), nil)
+				h.Mkfile(t, "3", filepath.Join(appDir, "build-status-A-v1"))
+				config.LogTail = 5
+
+				_, err := bpTOML.Build(buildpack.Plan{}, config, mockEnv)
+				if err, ok := err.(*buildpack.Error); !ok || err.Type != buildpack.ErrTypeBuildpack {
+					t.Fatalf("Incorrect error: %s\n", err)
+				}
+				h.AssertStringContains(t, err.Error(), "buildpack 'A@v1' failed: exit status 3\nLast 2 line(s) of output:\n")
+				h.AssertStringContains(t, err.Error(), "\n  build out: A@v1")
+				h.AssertStringContains(t, err.Error(), "\n  build err: A@v1")
+				if s := cmp.Diff(h.CleanEndings(stdout.String()), "build out: A@v1\n"); s != "" {
+					t.Fatalf("Unexpected stdout:\n%s\n", s)
+				}
+			})
+
+			it("should write the output to a file in the logs dir", func() {
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), "TEST_ENV=Av1"), nil)
+				h.Mkfile(t, "1", filepath.Join(appDir, "build-status-A-v1"))
+				config.LogsDir = filepath.Join(tmpDir, "logs")
+				logPath := filepath.Join(tmpDir, "logs", "A.log")
+
+				_, err := bpTOML.Build(buildpack.Plan{}, config, mockEnv)
+				h.AssertNotNil(t, err)
+				h.AssertStringContains(t, err.Error(), "\nFull output: "+logPath)
+				h.AssertStringDoesNotContain(t, err.Error(), "Last")
+				contents := h.CleanEndings(h.Rdfile(t, logPath))
+				h.AssertStringContains(t, contents, "build out: A@v1\n")
+				h.AssertStringContains(t, contents, "build err: A@v1\n")
+			})
+
+			it("should error when the command exceeds its timeout", func() {
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), "TEST_ENV=Av1"), nil)
+				h.Mkfile(t, "10", filepath.Join(appDir, "build-sleep"))
+				config.Timeouts = buildpack.Timeouts{Default: 100 * time.Millisecond}
+
+				start := time.Now()
+				br, err := bpTOML.Build(buildpack.Plan{}, config, mockEnv)
+
+				if br.Usage.Duration < 100*time.Millisecond {
+					t.Fatalf("Expected usage of the killed build to be reported, got %s", br.Usage.Duration)
+				}
+				if elapsed := time.Since(start); elapsed > 5*time.Second {
+					t.Fatalf("Expected build to be killed, but it ran for %s", elapsed)
+				}
+				if err, ok := err.(*buildpack.Error); !ok || err.Type != buildpack.ErrTypeBuildpack {
+					t.Fatalf("Incorrect error: %s\n", err)
+				}
+				h.AssertStringContains(t, err.Error(), "timed out after 100ms")
+			})
+
+			it("should error with an interrupted error when the context is done", func() {
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), "TEST_ENV=Av1"), nil)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				config.Context = ctx
+
+				_, err := bpTOML.Build(buildpack.Plan{}, config, mockEnv)
+				if err, ok := err.(*buildpack.Error); !ok || err.Type != buildpack.ErrTypeInterrupted {
+					t.Fatalf("Incorrect error: %s\n", err)
+				}
+			})
+
 			when("modifying the env fails", func() {
 				var appendErr error
 
@@ -791,7 +904,7 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 						},
 						Processes: nil,
 						Slices:    nil,
-					}); s != "" {
+					}, ignoreUsage); s != "" {
 						t.Fatalf("Unexpected:\n%s\n", s)
 					}
 				})
@@ -905,7 +1018,7 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 						{Type: "type-with-default", Command: "other-cmd", BuildpackID: "A", Default: false},
 					},
 					Slices: []layers.Slice{},
-				}); s != "" {
+				}, ignoreUsage); s != "" {
 					t.Fatalf("Unexpected metadata:\n%s\n", s)
 				}
 				expected := "Warning: default processes aren't supported in this buildpack api version. Overriding the default value to false for the following processes: [type-with-default]"
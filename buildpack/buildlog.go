@@ -0,0 +1,129 @@
+package buildpack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/launch"
+)
+
+// buildLog captures the combined output of a buildpack's bin/build: the last lines are kept in memory so that they
+// can be attached to a failure, and, if a logs directory is configured, the full output is written to a file there.
+type buildLog struct {
+	path string
+	file *os.File
+	tail *tailBuffer
+	mu   sync.Mutex
+}
+
+func openBuildLog(logsDir, bpID string, tailLines int) (*buildLog, error) {
+	l := &buildLog{tail: &tailBuffer{max: tailLines}}
+	if logsDir == "" {
+		return l, nil
+	}
+	if err := os.MkdirAll(logsDir, 0777); err != nil {
+		return nil, errors.Wrap(err, "create logs directory")
+	}
+	l.path = filepath.Join(logsDir, launch.EscapeID(bpID)+".log")
+	var err error
+	if l.file, err = os.Create(l.path); err != nil {
+		return nil, errors.Wrap(err, "create build log")
+	}
+	return l, nil
+}
+
+// Write is called for both stdout and stderr, which the exec package copies from separate goroutines.
+func (l *buildLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tail.Write(p)
+	if l.file != nil {
+		return l.file.Write(p)
+	}
+	return len(p), nil
+}
+
+// tee returns a writer that writes to both w, which may be nil, and the log.
+func (l *buildLog) tee(w io.Writer) io.Writer {
+	if w == nil {
+		return l
+	}
+	return io.MultiWriter(w, l)
+}
+
+func (l *buildLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// failure describes a failed build of the buildpack, including the end of its output.
+func (l *buildLog) failure(bpID, bpVersion string, err error) error {
+	msg := fmt.Sprintf("buildpack '%s@%s' failed: %s", bpID, bpVersion, err)
+	if lines := l.tail.Lines(); len(lines) > 0 {
+		msg += fmt.Sprintf("\nLast %d line(s) of output:\n  %s", len(lines), strings.Join(lines, "\n  "))
+	}
+	if l.path != "" {
+		msg += fmt.Sprintf("\nFull output: %s", l.path)
+	}
+	return errors.New(msg)
+}
+
+// maxLineLength is the most output without a newline that is buffered as a single line.
+const maxLineLength = 4 * 1024
+
+// tailBuffer keeps the last max lines written to it. Only the end of a line longer than maxLineLength is kept.
+type tailBuffer struct {
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	if t.max <= 0 {
+		return len(p), nil
+	}
+	t.partial = append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		t.add(strings.TrimSuffix(string(t.partial[:i]), "\r"))
+		t.partial = t.partial[i+1:]
+	}
+	if len(t.partial) > maxLineLength {
+		t.partial = append([]byte{}, t.partial[len(t.partial)-maxLineLength:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) add(line string) {
+	if len(line) > maxLineLength {
+		line = line[len(line)-maxLineLength:]
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+// Lines returns the last lines written, including a final line without a newline.
+func (t *tailBuffer) Lines() []string {
+	lines := t.lines
+	if len(t.partial) > 0 {
+		lines = append(append([]string{}, lines...), string(t.partial))
+		if len(lines) > t.max {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
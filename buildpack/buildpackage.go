@@ -0,0 +1,458 @@
+package buildpack
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/archive"
+	"github.com/buildpacks/lifecycle/launch"
+)
+
+// BuildpackLayersLabel is the buildpackage label that maps each buildpack ID and version to the layer containing it.
+const BuildpackLayersLabel = "io.buildpacks.buildpack.layers"
+
+// BuildpackageStore looks up buildpacks in a buildpackage, either an OCI image layout directory or a .cnb archive
+// of one. Only the image metadata is read up front: the layer containing a buildpack is extracted the first time
+// the buildpack is looked up, into a private directory that only this store uses, and removed again if it does not
+// match its diff ID. Close removes the extracted buildpacks.
+type BuildpackageStore struct {
+	ExtractDir string // the private directory is created in ExtractDir, or in the default temp dir if it is empty
+	Path       string
+
+	blobs     blobSource
+	layers    map[string]map[string]buildpackageLayer // buildpack ID -> version -> layer
+	dir       string                                  // the private directory, once created
+	extracted map[v1.Hash]string                      // diff ID -> extracted layer directory
+	mu        sync.Mutex
+}
+
+type buildpackageLayer struct {
+	DiffID v1.Hash `json:"layerDiffID"`
+	digest v1.Hash
+}
+
+func NewBuildpackageStore(path, extractDir string) (*BuildpackageStore, error) {
+	blobs, err := openBlobSource(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open buildpackage '%s'", path)
+	}
+	bpLayers, err := readBuildpackLayers(blobs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read buildpackage '%s'", path)
+	}
+	return &BuildpackageStore{
+		ExtractDir: extractDir,
+		Path:       path,
+		blobs:      blobs,
+		layers:     bpLayers,
+		extracted:  map[v1.Hash]string{},
+	}, nil
+}
+
+// Lookup returns the buildpack with the given ID and version, extracting it if needed.
+// As with DirBuildpackStore, bpVersion may be a semver range or empty.
+func (s *BuildpackageStore) Lookup(bpID, bpVersion string) (Buildpack, error) {
+	var installed []string
+	for v := range s.layers[bpID] {
+		installed = append(installed, v)
+	}
+	if len(installed) == 0 {
+		return nil, errors.Errorf("buildpack '%s' is not in the buildpackage", bpID)
+	}
+	version, err := ResolveVersion(bpID, bpVersion, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	layerDir, err := s.extract(s.layers[bpID][version])
+	if err != nil {
+		return nil, errors.Wrapf(err, "extract buildpack '%s@%s'", bpID, version)
+	}
+	bpPath := filepath.Join(layerDir, "cnb", "buildpacks", launch.EscapeID(bpID), version)
+	bpTOML, err := readDescriptor(filepath.Join(bpPath, "buildpack.toml"))
+	if err != nil {
+		return nil, err
+	}
+	bpTOML.Dir = bpPath
+	bpTOML.Source = s.Path
+	return &bpTOML, nil
+}
+
+// extract extracts the layer into the store's private directory, unless this store already extracted it.
+// The extraction fails if the uncompressed layer does not match its diff ID.
+func (s *BuildpackageStore) extract(layer buildpackageLayer) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dest, ok := s.extracted[layer.DiffID]; ok {
+		return dest, nil
+	}
+	if s.dir == "" {
+		if s.ExtractDir != "" {
+			if err := os.MkdirAll(s.ExtractDir, 0777); err != nil {
+				return "", err
+			}
+		}
+		dir, err := ioutil.TempDir(s.ExtractDir, "cnb-buildpackages.") // created with mode 0700
+		if err != nil {
+			return "", err
+		}
+		s.dir = dir
+	}
+	dest := filepath.Join(s.dir, layer.DiffID.Algorithm, layer.DiffID.Hex)
+	if err := extractVerified(s.blobs, layer, dest); err != nil {
+		os.RemoveAll(dest)
+		return "", err
+	}
+	s.extracted[layer.DiffID] = dest
+	return dest, nil
+}
+
+func extractVerified(blobs blobSource, layer buildpackageLayer, dest string) error {
+	rc, err := blobs.open(blobPath(layer.digest))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	r, err := uncompressed(rc)
+	if err != nil {
+		return err
+	}
+	hasher, err := v1.Hasher(layer.DiffID.Algorithm)
+	if err != nil {
+		return err
+	}
+	r = io.TeeReader(r, hasher)
+	if err := extractLayer(r, dest); err != nil {
+		return err
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil { // the tar reader may stop before the end of the layer
+		return err
+	}
+	if diffID := hex.EncodeToString(hasher.Sum(nil)); diffID != layer.DiffID.Hex {
+		return errors.Errorf("layer has diff ID '%s:%s', expected '%s'", layer.DiffID.Algorithm, diffID, layer.DiffID)
+	}
+	return nil
+}
+
+// extractLayer extracts a buildpackage layer into dest. The layer is untrusted, so an entry is rejected if it would be
+// written outside dest, including through a symlink, and a symlink is rejected if its target is absolute or outside
+// dest. Modes are applied to each file rather than by changing the process umask, as other goroutines may be creating
+// files at the same time.
+func extractLayer(r io.Reader, dest string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	root, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return err
+	}
+	tr := archive.NewNormalizingTarReader(tar.NewReader(r))
+	if runtime.GOOS == "windows" {
+		tr.ExcludePaths([]string{"Hives"})
+		tr.Strip(`Files/`)
+	}
+	var dirModes []archive.PathMode
+	var links []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "read layer")
+		}
+		target := filepath.Join(root, filepath.FromSlash(hdr.Name))
+		if !isWithin(root, target) {
+			return errors.Errorf("layer entry '%s' is outside the layer", hdr.Name)
+		}
+		if target == root {
+			continue
+		}
+		if err := checkResolvesWithin(root, target); err != nil {
+			return errors.Wrapf(err, "layer entry '%s'", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirModes = append(dirModes, archive.PathMode{Path: target, Mode: hdr.FileInfo().Mode()})
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeLayerFile(tr, target, hdr.FileInfo().Mode()); err != nil {
+				return errors.Wrapf(err, "write layer entry '%s'", hdr.Name)
+			}
+		case tar.TypeSymlink:
+			linkTarget := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(linkTarget) || !isWithin(root, filepath.Join(filepath.Dir(target), linkTarget)) {
+				return errors.Errorf("layer entry '%s' links to '%s' outside the layer", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(linkTarget, target); err != nil {
+				return err
+			}
+			links = append(links, target)
+		default:
+			return errors.Errorf("layer entry '%s' has unsupported type %d", hdr.Name, hdr.Typeflag)
+		}
+	}
+	// a target within dest may still resolve outside it through another symlink
+	for _, link := range links {
+		if _, err := os.Stat(link); os.IsNotExist(err) {
+			continue // a dangling symlink was not written through, as that would have been rejected
+		}
+		if err := checkResolvesWithin(root, link); err != nil {
+			rel, _ := filepath.Rel(root, link)
+			return errors.Wrapf(err, "layer entry '%s'", filepath.ToSlash(rel))
+		}
+	}
+	for _, dirMode := range dirModes {
+		if err := os.Chmod(dirMode.Path, dirMode.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResolvesWithin fails if path, or its longest existing prefix, resolves to a location outside root.
+// A dangling symlink is also rejected.
+func checkResolvesWithin(root, path string) error {
+	existing := path
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	if !isWithin(root, resolved) {
+		return errors.New("resolves outside the layer")
+	}
+	return nil
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func writeLayerFile(r io.Reader, path string, mode os.FileMode) (err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Chmod(mode)
+}
+
+// Close removes the buildpacks extracted by the store.
+func (s *BuildpackageStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.dir)
+	s.dir = ""
+	s.extracted = map[v1.Hash]string{}
+	return err
+}
+
+func readBuildpackLayers(blobs blobSource) (map[string]map[string]buildpackageLayer, error) {
+	var index *v1.IndexManifest
+	if err := readBlob(blobs, "index.json", func(r io.Reader) (err error) {
+		index, err = v1.ParseIndexManifest(r)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	var manifestDesc *v1.Descriptor
+	for i := range index.Manifests {
+		if index.Manifests[i].MediaType.IsImage() {
+			manifestDesc = &index.Manifests[i]
+			break
+		}
+	}
+	if manifestDesc == nil {
+		return nil, errors.New("no image manifest in index.json")
+	}
+
+	var manifest *v1.Manifest
+	if err := readBlob(blobs, blobPath(manifestDesc.Digest), func(r io.Reader) (err error) {
+		manifest, err = v1.ParseManifest(r)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	var config *v1.ConfigFile
+	if err := readBlob(blobs, blobPath(manifest.Config.Digest), func(r io.Reader) (err error) {
+		config, err = v1.ParseConfigFile(r)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if len(config.RootFS.DiffIDs) != len(manifest.Layers) {
+		return nil, errors.New("image config diff IDs do not match manifest layers")
+	}
+
+	label, ok := config.Config.Labels[BuildpackLayersLabel]
+	if !ok {
+		return nil, errors.Errorf("missing label '%s'", BuildpackLayersLabel)
+	}
+	var bpLayers map[string]map[string]buildpackageLayer
+	if err := json.Unmarshal([]byte(label), &bpLayers); err != nil {
+		return nil, errors.Wrapf(err, "parse label '%s'", BuildpackLayersLabel)
+	}
+	for id, versions := range bpLayers {
+		for version, layer := range versions {
+			for i, diffID := range config.RootFS.DiffIDs {
+				if diffID == layer.DiffID {
+					layer.digest = manifest.Layers[i].Digest
+				}
+			}
+			if layer.digest == (v1.Hash{}) {
+				return nil, errors.Errorf("missing layer '%s' for buildpack '%s@%s'", layer.DiffID, id, version)
+			}
+			versions[version] = layer
+		}
+	}
+	return bpLayers, nil
+}
+
+func readBlob(blobs blobSource, name string, f func(io.Reader) error) error {
+	rc, err := blobs.open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return errors.Wrapf(f(rc), "parse '%s'", name)
+}
+
+func blobPath(digest v1.Hash) string {
+	return path.Join("blobs", digest.Algorithm, digest.Hex)
+}
+
+// uncompressed returns the contents of a layer blob, which may or may not be gzipped.
+func uncompressed(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+func isOCILayout(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "oci-layout"))
+	return err == nil
+}
+
+// blobSource opens files in an OCI image layout by their slash-separated path relative to the layout root.
+type blobSource interface {
+	open(name string) (io.ReadCloser, error)
+}
+
+func openBlobSource(path string) (blobSource, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return dirBlobs(path), nil
+	}
+	return indexTarBlobs(path)
+}
+
+type dirBlobs string
+
+func (d dirBlobs) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// tarBlobs reads files from an archived OCI image layout in place, using the offsets of each file in the archive.
+type tarBlobs struct {
+	path    string
+	entries map[string]tarEntry
+}
+
+type tarEntry struct {
+	offset, size int64
+}
+
+func indexTarBlobs(archivePath string) (*tarBlobs, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	blobs := &tarBlobs{path: archivePath, entries: map[string]tarEntry{}}
+	tr := tar.NewReader(f) // the tar reader seeks past file contents, so only headers are read
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return blobs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		blobs.entries[path.Clean(hdr.Name)] = tarEntry{offset: offset, size: hdr.Size}
+	}
+}
+
+func (t *tarBlobs) open(name string) (io.ReadCloser, error) {
+	entry, ok := t.entries[name]
+	if !ok {
+		return nil, errors.Errorf("'%s' not found in '%s'", name, t.path)
+	}
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, entry.offset, entry.size), f}, nil
+}
@@ -0,0 +1,369 @@
+package buildpack_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/launch"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestBuildpackageStore(t *testing.T) {
+	spec.Run(t, "BuildpackageStore", testBuildpackageStore, spec.Report(report.Terminal{}))
+}
+
+func testBuildpackageStore(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir     string
+		layoutDir  string
+		archive    string
+		extractDir string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "lifecycle.buildpackage")
+		h.AssertNil(t, err)
+		layoutDir = filepath.Join(tmpDir, "layout")
+		archive = filepath.Join(tmpDir, "buildpackage.cnb")
+		extractDir = filepath.Join(tmpDir, "extracted")
+
+		writeBuildpackage(t, layoutDir, archive, []testBuildpack{
+			{id: "some/bp", version: "1.0.0", gzip: true},
+			{id: "other-bp", version: "2.0.0"},
+		})
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	for _, kind := range []string{"an OCI image layout", "a .cnb archive"} {
+		kind := kind
+		when(kind, func() {
+			var store *buildpack.BuildpackageStore
+
+			it.Before(func() {
+				path := layoutDir
+				if kind == "a .cnb archive" {
+					path = archive
+				}
+				var err error
+				store, err = buildpack.NewBuildpackageStore(path, extractDir)
+				h.AssertNil(t, err)
+			})
+
+			it("extracts only the buildpacks that are looked up", func() {
+				if _, err := os.Stat(extractDir); !os.IsNotExist(err) {
+					t.Fatalf("Expected nothing to be extracted before lookup")
+				}
+
+				bp, err := store.Lookup("some/bp", "1.0.0")
+				h.AssertNil(t, err)
+
+				desc := bp.ConfigFile()
+				h.AssertEq(t, desc.Buildpack.ID, "some/bp")
+				h.AssertEq(t, desc.Buildpack.Version, "1.0.0")
+				h.AssertEq(t, h.Rdfile(t, filepath.Join(desc.Dir, "bin", "detect")), "detect some/bp")
+
+				extracted, err := filepath.Glob(filepath.Join(extractDir, "cnb-buildpackages.*", "sha256", "*"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(extracted), 1)
+			})
+
+			it("extracts into a private directory", func() {
+				bp, err := store.Lookup("some/bp", "1.0.0")
+				h.AssertNil(t, err)
+
+				privateDirs, err := filepath.Glob(filepath.Join(extractDir, "cnb-buildpackages.*"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(privateDirs), 1)
+				fi, err := os.Stat(privateDirs[0])
+				h.AssertNil(t, err)
+				if runtime.GOOS != "windows" {
+					h.AssertEq(t, fi.Mode().Perm(), os.FileMode(0700))
+				}
+				if !strings.HasPrefix(bp.ConfigFile().Dir, privateDirs[0]+string(filepath.Separator)) {
+					t.Fatalf("Expected '%s' to be in '%s'", bp.ConfigFile().Dir, privateDirs[0])
+				}
+			})
+
+			it("does not use buildpacks extracted by others", func() {
+				other, err := buildpack.NewBuildpackageStore(layoutDir, extractDir)
+				h.AssertNil(t, err)
+				defer other.Close()
+				first, err := other.Lookup("some/bp", "1.0.0")
+				h.AssertNil(t, err)
+				h.Mkfile(t, "planted", filepath.Join(first.ConfigFile().Dir, "bin", "detect"))
+
+				bp, err := store.Lookup("some/bp", "1.0.0")
+				h.AssertNil(t, err)
+				h.AssertEq(t, h.Rdfile(t, filepath.Join(bp.ConfigFile().Dir, "bin", "detect")), "detect some/bp")
+			})
+
+			it("removes the extracted buildpacks when closed", func() {
+				bp, err := store.Lookup("some/bp", "1.0.0")
+				h.AssertNil(t, err)
+
+				h.AssertNil(t, store.Close())
+				if _, err := os.Stat(bp.ConfigFile().Dir); !os.IsNotExist(err) {
+					t.Fatalf("Expected '%s' to be removed", bp.ConfigFile().Dir)
+				}
+				extracted, err := filepath.Glob(filepath.Join(extractDir, "*"))
+				h.AssertNil(t, err)
+				h.AssertEq(t, len(extracted), 0)
+			})
+
+			it("reuses extracted layers", func() {
+				first, err := store.Lookup("other-bp", "2.0.0")
+				h.AssertNil(t, err)
+				second, err := store.Lookup("other-bp", "2.0.0")
+				h.AssertNil(t, err)
+				h.AssertEq(t, first.ConfigFile().Dir, second.ConfigFile().Dir)
+			})
+
+			it("resolves version ranges", func() {
+				bp, err := store.Lookup("other-bp", "^2")
+				h.AssertNil(t, err)
+				h.AssertEq(t, bp.ConfigFile().Buildpack.Version, "2.0.0")
+			})
+
+			it("fails for buildpacks that are not in the buildpackage", func() {
+				_, err := store.Lookup("missing-bp", "1.0.0")
+				h.AssertError(t, err, "buildpack 'missing-bp' is not in the buildpackage")
+			})
+		})
+	}
+
+	when("a layer does not match its diff ID", func() {
+		it.Before(func() {
+			blobs, err := filepath.Glob(filepath.Join(layoutDir, "blobs", "sha256", "*"))
+			h.AssertNil(t, err)
+			for _, blob := range blobs {
+				contents := h.Rdfile(t, blob)
+				if strings.Contains(contents, "detect other-bp") {
+					h.Mkfile(t, strings.Replace(contents, "detect other-bp", "detect evil-bp!", 1), blob)
+				}
+			}
+		})
+
+		it("fails to look up the buildpack", func() {
+			store, err := buildpack.NewBuildpackageStore(layoutDir, extractDir)
+			h.AssertNil(t, err)
+			defer store.Close()
+
+			_, err = store.Lookup("other-bp", "2.0.0")
+			h.AssertError(t, err, "extract buildpack 'other-bp@2.0.0': layer has diff ID")
+			extracted, err := filepath.Glob(filepath.Join(extractDir, "cnb-buildpackages.*", "sha256", "*"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(extracted), 0)
+		})
+	})
+
+	when("a layer has entries outside the buildpack", func() {
+		var outside string
+
+		it.Before(func() {
+			if runtime.GOOS == "windows" {
+				t.Skip("creating symlinks requires privileges on Windows")
+			}
+			outside = filepath.Join(tmpDir, "outside")
+			h.Mkdir(t, outside)
+		})
+
+		lookup := func(extra ...tarEntry) error {
+			t.Helper()
+			layoutDir := filepath.Join(tmpDir, "malicious")
+			writeBuildpackage(t, layoutDir, filepath.Join(tmpDir, "malicious.cnb"), []testBuildpack{
+				{id: "some/bp", version: "1.0.0", extra: extra},
+			})
+			store, err := buildpack.NewBuildpackageStore(layoutDir, extractDir)
+			h.AssertNil(t, err)
+			defer store.Close()
+			_, err = store.Lookup("some/bp", "1.0.0")
+			return err
+		}
+
+		file := func(name string) tarEntry {
+			return tarEntry{hdr: tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg}, contents: "escaped"}
+		}
+
+		symlink := func(name, target string) tarEntry {
+			return tarEntry{hdr: tar.Header{Name: name, Linkname: target, Mode: 0777, Typeflag: tar.TypeSymlink}}
+		}
+
+		assertNothingOutside := func() {
+			t.Helper()
+			entries, err := ioutil.ReadDir(outside)
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(entries), 0)
+		}
+
+		it("rejects entries that escape the extraction directory", func() {
+			rel, err := filepath.Rel(extractDir, filepath.Join(outside, "file"))
+			h.AssertNil(t, err)
+			err = lookup(file("../../../" + filepath.ToSlash(rel)))
+			h.AssertStringContains(t, err.Error(), "is outside the layer")
+			assertNothingOutside()
+		})
+
+		it("rejects symlinks with absolute targets", func() {
+			err := lookup(symlink("cnb/link", outside), file("cnb/link/file"))
+			h.AssertStringContains(t, err.Error(), "layer entry 'cnb/link' links to '"+outside+"' outside the layer")
+			assertNothingOutside()
+		})
+
+		it("rejects symlinks with targets outside the extraction directory", func() {
+			err := lookup(symlink("cnb/link", "../../../.."), file("cnb/link/file"))
+			h.AssertStringContains(t, err.Error(), "outside the layer")
+			assertNothingOutside()
+		})
+
+		it("rejects symlinks that resolve outside the extraction directory through other symlinks", func() {
+			err := lookup(
+				symlink("cnb/buildpacks/up", ".."),
+				symlink("escape", "cnb/buildpacks/up/../.."),
+				file("escape/file"),
+			)
+			h.AssertStringContains(t, err.Error(), "resolves outside the layer")
+		})
+
+		it("allows symlinks within the buildpack", func() {
+			h.AssertNil(t, lookup(symlink("cnb/buildpacks/some_bp/1.0.0/bin/build", "detect")))
+		})
+	})
+
+	when("NewStore", func() {
+		it("returns a buildpackage store for OCI image layouts and archives", func() {
+			for _, path := range []string{layoutDir, archive} {
+				store, err := buildpack.NewStore(path)
+				h.AssertNil(t, err)
+				if _, ok := store.(*buildpack.BuildpackageStore); !ok {
+					t.Fatalf("Expected a buildpackage store for '%s', got %T", path, store)
+				}
+			}
+		})
+
+		it("returns a directory store for other directories", func() {
+			store, err := buildpack.NewStore(tmpDir)
+			h.AssertNil(t, err)
+			if _, ok := store.(*buildpack.DirBuildpackStore); !ok {
+				t.Fatalf("Expected a directory store, got %T", store)
+			}
+		})
+	})
+}
+
+type testBuildpack struct {
+	id, version string
+	gzip        bool
+	extra       []tarEntry // written to the layer after the buildpack's files
+}
+
+type tarEntry struct {
+	hdr      tar.Header
+	contents string
+}
+
+// writeBuildpackage writes a buildpackage containing the buildpacks as an OCI image layout and as a .cnb archive.
+func writeBuildpackage(t *testing.T, layoutDir, archive string, bps []testBuildpack) {
+	t.Helper()
+	h.Mkdir(t, filepath.Join(layoutDir, "blobs", "sha256"))
+	writeBlob := func(data []byte) v1.Hash {
+		t.Helper()
+		digest, _, err := v1.SHA256(bytes.NewReader(data))
+		h.AssertNil(t, err)
+		h.AssertNil(t, ioutil.WriteFile(filepath.Join(layoutDir, "blobs", "sha256", digest.Hex), data, 0600))
+		return digest
+	}
+
+	config := v1.ConfigFile{OS: "linux", RootFS: v1.RootFS{Type: "layers"}}
+	manifest := v1.Manifest{SchemaVersion: 2, MediaType: types.OCIManifestSchema1}
+	label := map[string]map[string]map[string]string{}
+	for _, bp := range bps {
+		dir := fmt.Sprintf("cnb/buildpacks/%s/%s", launch.EscapeID(bp.id), bp.version)
+		layer := tarFiles(t, map[string]string{
+			dir + "/buildpack.toml": fmt.Sprintf("api = \"0.6\"\n[buildpack]\nid = \"%s\"\nversion = \"%s\"\n", bp.id, bp.version),
+			dir + "/bin/detect":     "detect " + bp.id,
+		}, bp.extra...)
+		diffID, _, err := v1.SHA256(bytes.NewReader(layer))
+		h.AssertNil(t, err)
+		mediaType := types.OCIUncompressedLayer
+		if bp.gzip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			_, err := zw.Write(layer)
+			h.AssertNil(t, err)
+			h.AssertNil(t, zw.Close())
+			layer, mediaType = buf.Bytes(), types.OCILayer
+		}
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, diffID)
+		manifest.Layers = append(manifest.Layers, v1.Descriptor{MediaType: mediaType, Size: int64(len(layer)), Digest: writeBlob(layer)})
+		label[bp.id] = map[string]map[string]string{bp.version: {"api": "0.6", "layerDiffID": diffID.String()}}
+	}
+	labelJSON, err := json.Marshal(label)
+	h.AssertNil(t, err)
+	config.Config.Labels = map[string]string{buildpack.BuildpackLayersLabel: string(labelJSON)}
+
+	configJSON, err := json.Marshal(config)
+	h.AssertNil(t, err)
+	manifest.Config = v1.Descriptor{MediaType: types.OCIConfigJSON, Size: int64(len(configJSON)), Digest: writeBlob(configJSON)}
+	manifestJSON, err := json.Marshal(manifest)
+	h.AssertNil(t, err)
+	index := v1.IndexManifest{SchemaVersion: 2, Manifests: []v1.Descriptor{
+		{MediaType: types.OCIManifestSchema1, Size: int64(len(manifestJSON)), Digest: writeBlob(manifestJSON)},
+	}}
+	indexJSON, err := json.Marshal(index)
+	h.AssertNil(t, err)
+	h.Mkfile(t, string(indexJSON), filepath.Join(layoutDir, "index.json"))
+	h.Mkfile(t, `{"imageLayoutVersion": "1.0.0"}`, filepath.Join(layoutDir, "oci-layout"))
+
+	files := map[string]string{}
+	h.AssertNil(t, filepath.Walk(layoutDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(layoutDir, path)
+		if err != nil {
+			return err
+		}
+		files["./"+filepath.ToSlash(rel)] = h.Rdfile(t, path)
+		return nil
+	}))
+	h.AssertNil(t, ioutil.WriteFile(archive, tarFiles(t, files), 0600))
+}
+
+func tarFiles(t *testing.T, files map[string]string, extra ...tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, contents := range files {
+		h.AssertNil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(contents))
+		h.AssertNil(t, err)
+	}
+	for _, entry := range extra {
+		hdr := entry.hdr
+		hdr.Size = int64(len(entry.contents))
+		h.AssertNil(t, tw.WriteHeader(&hdr))
+		_, err := tw.Write([]byte(entry.contents))
+		h.AssertNil(t, err)
+	}
+	h.AssertNil(t, tw.Close())
+	return buf.Bytes()
+}
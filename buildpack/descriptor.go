@@ -2,11 +2,31 @@
 
 package buildpack
 
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
 type Descriptor struct {
-	API       string `toml:"api"`
-	Buildpack Info   `toml:"buildpack"`
-	Order     Order  `toml:"order"`
-	Dir       string `toml:"-"`
+	API         string       `toml:"api"`
+	Buildpack   Info         `toml:"buildpack"`
+	Order       Order        `toml:"order"`
+	Stacks      []Stack      `toml:"stacks"`
+	DetectRules *DetectRules `toml:"detect"` // when set, used instead of bin/detect
+	Dir         string       `toml:"-"`
+	Source      string       `toml:"-"` // the buildpacks directory or buildpackage the buildpack was found in
+}
+
+// readDescriptor reads the buildpack.toml at path, failing if it declares invalid detect rules.
+func readDescriptor(path string) (Descriptor, error) {
+	var bpTOML Descriptor
+	if _, err := toml.DecodeFile(path, &bpTOML); err != nil {
+		return Descriptor{}, err
+	}
+	if err := bpTOML.DetectRules.validate(); err != nil {
+		return Descriptor{}, errors.Wrapf(err, "read '%s'", path)
+	}
+	return bpTOML, nil
 }
 
 func (b *Descriptor) ConfigFile() *Descriptor {
@@ -2,11 +2,16 @@ package buildpack
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/pkg/errors"
@@ -16,6 +21,11 @@ import (
 
 const EnvBuildpackDir = "CNB_BUILDPACK_DIR"
 
+const (
+	CodeDetectPass = 0
+	CodeDetectFail = 100
+)
+
 type Logger interface {
 	Debug(msg string)
 	Debugf(fmt string, v ...interface{})
@@ -34,6 +44,37 @@ type DetectConfig struct {
 	AppDir      string
 	PlatformDir string
 	Logger      Logger
+	Timeouts    Timeouts
+	Context     context.Context // when done, running detect processes are killed; may be nil
+	StackID     string          // when set, buildpacks that do not support the stack fail detection
+	BuildMixins []string        // mixins of the build image; nil if unknown
+	Stream      *LineStream     // when set, detect output is also streamed here as it is produced
+	Offline     bool            // when set, bin/detect runs without network access (Linux only)
+}
+
+// DetectRules declare detection that is evaluated in-process instead of by executing bin/detect: the buildpack passes
+// detection with the declared build plan if every glob in Files and no glob in ExcludeFiles matches a path in the
+// app directory. Globs use filepath.Match syntax and are relative to the app directory.
+type DetectRules struct {
+	Files        []string `toml:"files"`
+	ExcludeFiles []string `toml:"exclude-files"`
+	BuildPlan
+}
+
+// validate fails if a glob is absolute or may match paths outside the app directory.
+func (r *DetectRules) validate() error {
+	if r == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, r.Files...), r.ExcludeFiles...) {
+		if filepath.IsAbs(pattern) || strings.HasPrefix(filepath.ToSlash(pattern), "/") {
+			return errors.Errorf("detect rule '%s' must be relative to the app directory", pattern)
+		}
+		if clean := filepath.Clean(pattern); clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return errors.Errorf("detect rule '%s' is outside the app directory", pattern)
+		}
+	}
+	return nil
 }
 
 func (b *Descriptor) Detect(config *DetectConfig, bpEnv BuildEnv) DetectRun {
@@ -41,6 +82,9 @@ func (b *Descriptor) Detect(config *DetectConfig, bpEnv BuildEnv) DetectRun {
 	if err != nil {
 		return DetectRun{Code: -1, Err: err}
 	}
+	if b.DetectRules.declared() {
+		return b.detectRules(config, appDir)
+	}
 	platformDir, err := filepath.Abs(config.PlatformDir)
 	if err != nil {
 		return DetectRun{Code: -1, Err: err}
@@ -65,6 +109,13 @@ func (b *Descriptor) Detect(config *DetectConfig, bpEnv BuildEnv) DetectRun {
 	cmd.Dir = appDir
 	cmd.Stdout = out
 	cmd.Stderr = out
+	if config.Stream != nil {
+		streamed := config.Stream.Writer(b.Buildpack.ID)
+		defer streamed.Close()
+		combined := io.MultiWriter(out, streamed)
+		cmd.Stdout = combined
+		cmd.Stderr = combined
+	}
 
 	if b.Buildpack.ClearEnv {
 		cmd.Env = bpEnv.List()
@@ -75,19 +126,67 @@ func (b *Descriptor) Detect(config *DetectConfig, bpEnv BuildEnv) DetectRun {
 		}
 	}
 	cmd.Env = append(cmd.Env, EnvBuildpackDir+"="+b.Dir)
+	if config.Offline {
+		if err := isolateNetwork(cmd); err != nil {
+			return DetectRun{Code: -1, Err: err}
+		}
+	}
 
-	if err := cmd.Run(); err != nil {
+	start := time.Now()
+	err = RunCmd(config.Context, cmd, config.Timeouts.For(b.Buildpack.ID))
+	duration := time.Since(start)
+	if err != nil {
 		if err, ok := err.(*exec.ExitError); ok {
 			if status, ok := err.Sys().(syscall.WaitStatus); ok {
-				return DetectRun{Code: status.ExitStatus(), Output: out.Bytes()}
+				return DetectRun{Code: status.ExitStatus(), Output: out.Bytes(), Duration: duration}
 			}
 		}
-		return DetectRun{Code: -1, Err: err, Output: out.Bytes()}
+		return DetectRun{Code: -1, Err: err, Output: out.Bytes(), Duration: duration}
 	}
 	var t DetectRun
 	if _, err := toml.DecodeFile(planPath, &t); err != nil {
-		return DetectRun{Code: -1, Err: err, Output: out.Bytes()}
+		return DetectRun{Code: -1, Err: err, Output: out.Bytes(), Duration: duration}
+	}
+	b.validatePlan(config, &t)
+	t.Output = out.Bytes()
+	t.Duration = duration
+	return t
+}
+
+// declared reports whether any rules are declared. An empty [detect] table declares none, so bin/detect is used.
+func (r *DetectRules) declared() bool {
+	return r != nil && (len(r.Files) > 0 || len(r.ExcludeFiles) > 0 ||
+		len(r.Requires) > 0 || len(r.Provides) > 0 || len(r.Or) > 0)
+}
+
+func (b *Descriptor) detectRules(config *DetectConfig, appDir string) DetectRun {
+	start := time.Now()
+	for _, pattern := range b.DetectRules.Files {
+		matches, err := filepath.Glob(filepath.Join(appDir, pattern))
+		if err != nil {
+			return DetectRun{Code: -1, Err: errors.Wrapf(err, "detect rules of buildpack %s", b.Buildpack.ID)}
+		}
+		if len(matches) == 0 {
+			return DetectRun{Code: CodeDetectFail, Reason: fmt.Sprintf("no file matches '%s'", pattern), Duration: time.Since(start)}
+		}
 	}
+	for _, pattern := range b.DetectRules.ExcludeFiles {
+		matches, err := filepath.Glob(filepath.Join(appDir, pattern))
+		if err != nil {
+			return DetectRun{Code: -1, Err: errors.Wrapf(err, "detect rules of buildpack %s", b.Buildpack.ID)}
+		}
+		if len(matches) > 0 {
+			return DetectRun{Code: CodeDetectFail, Reason: fmt.Sprintf("excluded file '%s' exists", pattern), Duration: time.Since(start)}
+		}
+	}
+	t := DetectRun{BuildPlan: b.DetectRules.BuildPlan.copy()}
+	b.validatePlan(config, &t)
+	t.Duration = time.Since(start)
+	return t
+}
+
+// validatePlan fails the run if its build plan uses versions in a way the buildpack API does not allow.
+func (b *Descriptor) validatePlan(config *DetectConfig, t *DetectRun) {
 	if api.MustParse(b.API).Equal(api.MustParse("0.2")) {
 		if t.hasInconsistentVersions() || t.Or.hasInconsistentVersions() {
 			t.Err = errors.Errorf(`buildpack %s has a "version" key that does not match "metadata.version"`, b.Buildpack.ID)
@@ -105,13 +204,14 @@ func (b *Descriptor) Detect(config *DetectConfig, bpEnv BuildEnv) DetectRun {
 			config.Logger.Warnf(`Warning: buildpack %s has a "version" key. This key is deprecated in build plan requirements in buildpack API 0.3. "metadata.version" should be used instead`, b.Buildpack.ID)
 		}
 	}
-	t.Output = out.Bytes()
-	return t
 }
 
 type DetectRun struct {
 	BuildPlan
-	Output []byte `toml:"-"`
-	Code   int    `toml:"-"`
-	Err    error  `toml:"-"`
+	Output   []byte        `toml:"-"`
+	Code     int           `toml:"-"`
+	Err      error         `toml:"-"`
+	Duration time.Duration `toml:"-"`
+	Source   string        `toml:"-"`
+	Reason   string        `toml:"-"` // why detection failed without running bin/detect
 }
@@ -1,13 +1,17 @@
 package buildpack_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/memory"
 	"github.com/golang/mock/gomock"
@@ -145,6 +149,149 @@ func testDetect(t *testing.T, when spec.G, it spec.S) {
 			}
 		})
 
+		it("should stream output prefixed with the buildpack ID when configured", func() {
+			mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), someEnv), nil)
+			streamed := &bytes.Buffer{}
+			detectConfig.Stream = &buildpack.LineStream{W: streamed}
+
+			detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+			h.AssertEq(t, detectRun.Code, 0)
+			h.AssertStringContains(t, string(detectRun.Output), "detect out: A@v1")
+			h.AssertStringContains(t, streamed.String(), "[A] detect out: A@v1\n")
+			h.AssertStringContains(t, streamed.String(), "[A] detect err: A@v1\n")
+		})
+
+		when("a timeout is configured", func() {
+			it("should kill the buildpack and fail when the timeout elapses", func() {
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), someEnv), nil)
+				toappfile("10", "detect-sleep")
+				detectConfig.Timeouts = buildpack.Timeouts{Default: 100 * time.Millisecond}
+
+				start := time.Now()
+				detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+				if elapsed := time.Since(start); elapsed > 5*time.Second {
+					t.Fatalf("Expected detect to be killed, but it ran for %s", elapsed)
+				}
+				h.AssertEq(t, detectRun.Code, -1)
+				h.AssertStringContains(t, detectRun.Err.Error(), "timed out after 100ms")
+				h.AssertStringContains(t, string(detectRun.Output), "detect out: A@v1")
+			})
+
+			it("should use the timeout for the buildpack ID when present", func() {
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), someEnv), nil)
+				toappfile("1", "detect-sleep")
+				detectConfig.Timeouts = buildpack.Timeouts{
+					Default: 100 * time.Millisecond,
+					ByID:    map[string]time.Duration{"A": time.Minute},
+				}
+
+				detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+				h.AssertNil(t, detectRun.Err)
+				h.AssertEq(t, detectRun.Code, 0)
+			})
+		})
+
+		it("should not run the buildpack when the context is done", func() {
+			mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), someEnv), nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			detectConfig.Context = ctx
+
+			detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+			h.AssertEq(t, detectRun.Code, -1)
+			if detectRun.Err != context.Canceled {
+				t.Fatalf("Unexpected error: %s\n", detectRun.Err)
+			}
+			if _, err := os.Stat(filepath.Join(detectConfig.AppDir, "detect-env-type-A-v1")); !os.IsNotExist(err) {
+				t.Fatalf("Expected detect not to run")
+			}
+		})
+
+		when("detect rules are declared", func() {
+			it.Before(func() {
+				_, err := toml.Decode(`
+[detect]
+files = ["package.json", "*.js"]
+exclude-files = ["yarn.lock"]
+
+[[detect.provides]]
+name = "node"
+
+[[detect.requires]]
+name = "node"
+[detect.requires.metadata]
+version = "16.x"
+`, &bpTOML)
+				h.AssertNil(t, err)
+			})
+
+			it("should pass with the declared plan without running bin/detect", func() {
+				toappfile("{}", "package.json")
+				toappfile("", "index.js")
+
+				detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+				h.AssertNil(t, detectRun.Err)
+				h.AssertEq(t, detectRun.Code, 0)
+				h.AssertEq(t, detectRun.Provides, []buildpack.Provide{{Name: "node"}})
+				h.AssertEq(t, detectRun.Requires, []buildpack.Require{{Name: "node", Metadata: map[string]interface{}{"version": "16.x"}}})
+				if _, err := os.Stat(filepath.Join(detectConfig.AppDir, "detect-env-type-A-v1")); !os.IsNotExist(err) {
+					t.Fatalf("Expected bin/detect not to run")
+				}
+			})
+
+			it("should not share the declared plan with the run", func() {
+				toappfile("{}", "package.json")
+				toappfile("", "index.js")
+
+				detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+				detectRun.Requires[0].Name = "other"
+
+				h.AssertEq(t, bpTOML.DetectRules.Requires[0].Name, "node")
+			})
+
+			it("should fail when a required file is missing", func() {
+				toappfile("{}", "package.json")
+
+				detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+				h.AssertEq(t, detectRun.Code, 100)
+				h.AssertEq(t, detectRun.Reason, "no file matches '*.js'")
+			})
+
+			it("should fail when an excluded file exists", func() {
+				toappfile("{}", "package.json")
+				toappfile("", "index.js", "yarn.lock")
+
+				detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+				h.AssertEq(t, detectRun.Code, 100)
+				h.AssertEq(t, detectRun.Reason, "excluded file 'yarn.lock' exists")
+			})
+		})
+
+		when("the detect table is empty", func() {
+			it.Before(func() {
+				_, err := toml.Decode("[detect]\n", &bpTOML)
+				h.AssertNil(t, err)
+			})
+
+			it("should run bin/detect", func() {
+				mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), someEnv), nil)
+
+				detectRun := bpTOML.Detect(&detectConfig, mockEnv)
+
+				h.AssertNil(t, detectRun.Err)
+				if _, err := os.Stat(filepath.Join(detectConfig.AppDir, "detect-env-type-A-v1")); err != nil {
+					t.Fatalf("Expected bin/detect to run: %s", err)
+				}
+			})
+		})
+
 		it("should fail and print the output if the buildpack plan file has a bad format", func() {
 			mockEnv.EXPECT().WithPlatform(platformDir).Return(append(os.Environ(), someEnv), nil)
 
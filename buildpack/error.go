@@ -4,6 +4,9 @@ type ErrorType string
 
 const ErrTypeBuildpack ErrorType = "ERR_BUILDPACK"
 const ErrTypeFailedDetection ErrorType = "ERR_FAILED_DETECTION"
+const ErrTypeOrderCycle ErrorType = "ERR_ORDER_CYCLE"
+const ErrTypeInterrupted ErrorType = "ERR_INTERRUPTED"
+const ErrTypeUntrusted ErrorType = "ERR_UNTRUSTED"
 
 type Error struct {
 	RootError error
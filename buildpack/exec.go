@@ -0,0 +1,117 @@
+package buildpack
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IsolatedExecName is the name the lifecycle is executed with to run a buildpack with the network isolated.
+// The lifecycle must call IsolatedExec when it is executed with this name.
+const IsolatedExecName = "cnb-isolated-exec"
+
+// Timeouts limit how long a buildpack's bin/detect or bin/build may run.
+// A zero duration means no limit.
+type Timeouts struct {
+	Default time.Duration
+	ByID    map[string]time.Duration
+}
+
+// ParseTimeouts parses a comma separated list of durations of the form "<default>,<buildpack-id>=<duration>,...",
+// e.g. "10m,example/node=30m". Either part may be omitted.
+func ParseTimeouts(s string) (Timeouts, error) {
+	var t Timeouts
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 1 {
+			d, err := time.ParseDuration(kv[0])
+			if err != nil {
+				return Timeouts{}, errors.Wrapf(err, "parse default timeout '%s'", kv[0])
+			}
+			t.Default = d
+			continue
+		}
+		d, err := time.ParseDuration(kv[1])
+		if err != nil {
+			return Timeouts{}, errors.Wrapf(err, "parse timeout for buildpack '%s'", kv[0])
+		}
+		if t.ByID == nil {
+			t.ByID = map[string]time.Duration{}
+		}
+		t.ByID[kv[0]] = d
+	}
+	return t, nil
+}
+
+// For returns the timeout that applies to the buildpack with the given ID.
+func (t Timeouts) For(bpID string) time.Duration {
+	if d, ok := t.ByID[bpID]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// RunCmd starts the command in its own process group and waits for it to exit.
+// If the timeout elapses or the context is done first, the entire process group is killed.
+// It is used to run buildpack executables and the platform's build hooks.
+func RunCmd(ctx context.Context, cmd *exec.Cmd, timeout time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-expired:
+		_ = killProcessGroup(cmd)
+		<-done
+		return fmt.Errorf("%s timed out after %s", cmd.Path, timeout)
+	case <-ctx.Done():
+		_ = killProcessGroup(cmd)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// processUsage returns the CPU time and peak memory of an exited process, or zero values if it did not start.
+func processUsage(state *os.ProcessState) Usage {
+	if state == nil {
+		return Usage{}
+	}
+	return Usage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+		MaxRSS:     maxRSS(state),
+	}
+}
+
+func interrupted(ctx context.Context) bool {
+	return ctx != nil && ctx.Err() != nil
+}
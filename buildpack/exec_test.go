@@ -0,0 +1,60 @@
+package buildpack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTimeouts(t *testing.T) {
+	spec.Run(t, "Timeouts", testTimeouts, spec.Report(report.Terminal{}))
+}
+
+func testTimeouts(t *testing.T, when spec.G, it spec.S) {
+	when("#ParseTimeouts", func() {
+		it("parses a default timeout", func() {
+			timeouts, err := buildpack.ParseTimeouts("10m")
+			h.AssertNil(t, err)
+			h.AssertEq(t, timeouts, buildpack.Timeouts{Default: 10 * time.Minute})
+		})
+
+		it("parses per-buildpack timeouts", func() {
+			timeouts, err := buildpack.ParseTimeouts("10m, some/bp=1h,other-bp=30s")
+			h.AssertNil(t, err)
+			h.AssertEq(t, timeouts, buildpack.Timeouts{
+				Default: 10 * time.Minute,
+				ByID: map[string]time.Duration{
+					"some/bp":  time.Hour,
+					"other-bp": 30 * time.Second,
+				},
+			})
+		})
+
+		it("returns no timeouts for an empty string", func() {
+			timeouts, err := buildpack.ParseTimeouts("")
+			h.AssertNil(t, err)
+			h.AssertEq(t, timeouts, buildpack.Timeouts{})
+		})
+
+		it("fails for an invalid duration", func() {
+			_, err := buildpack.ParseTimeouts("some/bp=forever")
+			h.AssertError(t, err, "parse timeout for buildpack 'some/bp'")
+		})
+	})
+
+	when("#For", func() {
+		it("returns the buildpack timeout or the default", func() {
+			timeouts := buildpack.Timeouts{
+				Default: time.Minute,
+				ByID:    map[string]time.Duration{"some/bp": time.Hour},
+			}
+			h.AssertEq(t, timeouts.For("some/bp"), time.Hour)
+			h.AssertEq(t, timeouts.For("other/bp"), time.Minute)
+		})
+	})
+}
@@ -0,0 +1,25 @@
+package buildpack
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killProcessGroup kills the process and all of its descendants
+func killProcessGroup(cmd *exec.Cmd) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil { // #nosec G204
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// maxRSS is not reported on Windows.
+func maxRSS(_ *os.ProcessState) int64 {
+	return 0
+}
@@ -34,6 +34,37 @@ type Require struct {
 	Metadata map[string]interface{} `toml:"metadata" json:"metadata"`
 }
 
+// VersionConstraint returns the version constraint of the require, taken from "metadata.version" or the
+// deprecated top-level "version" key.
+func (r Require) VersionConstraint() string {
+	if r.Version != "" {
+		return r.Version
+	}
+	if version, ok := r.Metadata["version"]; ok {
+		return fmt.Sprintf("%v", version)
+	}
+	return ""
+}
+
+// SatisfiedBy reports whether the provide's version satisfies the require's version constraint.
+// A provided partial version such as 14 is compared as 14.0.0, and a provided version that is not a semantic version
+// does not satisfy a constraint. Requires without a valid constraint and provides without a version are not compared,
+// so build plans that don't use semantic versions resolve as before.
+func (r Require) SatisfiedBy(p Provide) bool {
+	if p.Version == "" || r.VersionConstraint() == "" {
+		return true
+	}
+	constraint, err := parseConstraint(r.VersionConstraint())
+	if err != nil {
+		return true
+	}
+	version, err := parsePartialSemver(p.Version)
+	if err != nil {
+		return false
+	}
+	return constraint.matches(version)
+}
+
 func (r *Require) convertMetadataToVersion() {
 	if version, ok := r.Metadata["version"]; ok {
 		r.Version = fmt.Sprintf("%v", version)
@@ -97,6 +128,15 @@ type BuildPlan struct {
 	Or planSectionsList `toml:"or"`
 }
 
+// copy returns a plan that shares no slices with p, so that appending to either does not affect the other.
+func (p BuildPlan) copy() BuildPlan {
+	c := BuildPlan{PlanSections: p.PlanSections.copy()}
+	for _, sections := range p.Or {
+		c.Or = append(c.Or, sections.copy())
+	}
+	return c
+}
+
 func (p *PlanSections) hasInconsistentVersions() bool {
 	for _, req := range p.Requires {
 		if req.hasInconsistentVersions() {
@@ -158,8 +198,16 @@ type PlanSections struct {
 	Provides []Provide `toml:"provides"`
 }
 
+func (p PlanSections) copy() PlanSections {
+	return PlanSections{
+		Requires: append([]Require(nil), p.Requires...),
+		Provides: append([]Provide(nil), p.Provides...),
+	}
+}
+
 type Provide struct {
-	Name string `toml:"name"`
+	Name    string `toml:"name"`
+	Version string `toml:"version,omitempty"`
 }
 
 // buildpack plan
@@ -0,0 +1,121 @@
+// +build linux
+
+package buildpack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// isolateNetwork makes cmd run in a new network namespace in which only the loopback interface is up.
+// An unprivileged process can only create a network namespace inside a new user namespace, in which it is root, and
+// only root can bring up the loopback interface, so cmd is run by an IsolatedExecName helper: the lifecycle
+// re-executed as root in the new namespaces. The helper brings up loopback and then runs cmd in a nested user
+// namespace that maps it back to the original user.
+func isolateNetwork(cmd *exec.Cmd) error {
+	self, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "find lifecycle executable")
+	}
+	cmd.Args = append([]string{IsolatedExecName, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	return nil
+}
+
+// IsolatedExec is the entry point of the IsolatedExecName helper. It runs the command in os.Args[1:] with the
+// network isolated and exits with its status.
+func IsolatedExec() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "no command to run")
+		os.Exit(1)
+	}
+	if err := loopbackUp(); err != nil {
+		fmt.Fprintf(os.Stderr, "bring up loopback interface: %s\n", err)
+		os.Exit(1)
+	}
+	uid, err := outerID("/proc/self/uid_map")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read uid map: %s\n", err)
+		os.Exit(1)
+	}
+	gid, err := outerID("/proc/self/gid_map")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read gid map: %s\n", err)
+		os.Exit(1)
+	}
+
+	cmd := exec.Command(os.Args[1], os.Args[2:]...) // #nosec G204
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: uid, HostID: 0, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: gid, HostID: 0, Size: 1}},
+	}
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			os.Exit(128 + int(status.Signal()))
+		}
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// outerID returns the ID in the parent user namespace that is mapped to root by a single-line ID map.
+func outerID(mapPath string) (int, error) {
+	contents, err := ioutil.ReadFile(mapPath)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(contents))
+	if len(fields) != 3 || fields[0] != "0" {
+		return 0, errors.Errorf("unexpected ID map '%s'", strings.TrimSpace(string(contents)))
+	}
+	return strconv.Atoi(fields[1])
+}
+
+// loopbackUp sets the IFF_UP flag of the loopback interface.
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var req struct {
+		name  [syscall.IFNAMSIZ]byte
+		flags uint16
+		_     [22]byte // the rest of struct ifreq
+	}
+	copy(req.name[:], "lo")
+	if err := ioctl(fd, syscall.SIOCGIFFLAGS, unsafe.Pointer(&req)); err != nil {
+		return err
+	}
+	req.flags |= syscall.IFF_UP
+	return ioctl(fd, syscall.SIOCSIFFLAGS, unsafe.Pointer(&req))
+}
+
+func ioctl(fd int, req uint, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+// +build !linux
+
+package buildpack
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+func isolateNetwork(_ *exec.Cmd) error {
+	return errors.New("network isolation is only supported on Linux")
+}
+
+func IsolatedExec() {
+	fmt.Fprintln(os.Stderr, "network isolation is only supported on Linux")
+	os.Exit(1)
+}
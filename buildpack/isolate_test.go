@@ -0,0 +1,17 @@
+package buildpack_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+)
+
+// TestMain lets the test binary act as the lifecycle when it is re-executed to run a buildpack offline.
+func TestMain(m *testing.M) {
+	if filepath.Base(os.Args[0]) == buildpack.IsolatedExecName {
+		buildpack.IsolatedExec()
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,300 @@
+package buildpack
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version constraints follow the npm range syntax: comparators (=, >, >=, <, <=) are joined by whitespace or
+// commas, alternatives are separated by "||", and partial versions (14, 14.x, 14.2.*), tilde (~1.2), caret (^1.2.3)
+// and hyphen (1.2.3 - 2.3.4) ranges are expanded to the equivalent comparators.
+
+var (
+	semverRegex     = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+	partialRegex    = regexp.MustCompile(`^v?([0-9]+|[xX*])(?:\.([0-9]+|[xX*]))?(?:\.([0-9]+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+	comparatorRegex = regexp.MustCompile(`^(>=|<=|>|<|=|~>|~|\^)?\s*([^\s,]*)`)
+	hyphenRegex     = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+)
+
+type semver struct {
+	major, minor, patch uint64
+	pre                 []string
+}
+
+func parseSemver(s string) (semver, error) {
+	m := semverRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return semver{}, errors.Errorf("could not parse '%s' as a semantic version", s)
+	}
+	v := semver{}
+	v.major, _ = strconv.ParseUint(m[1], 10, 64)
+	v.minor, _ = strconv.ParseUint(m[2], 10, 64)
+	v.patch, _ = strconv.ParseUint(m[3], 10, 64)
+	if m[4] != "" {
+		v.pre = strings.Split(m[4], ".")
+	}
+	return v, nil
+}
+
+// parsePartialSemver parses a version that may omit its minor and patch numbers, which default to zero,
+// so that 14 is parsed as 14.0.0 and 14.2 as 14.2.0.
+func parsePartialSemver(s string) (semver, error) {
+	if v, err := parseSemver(s); err == nil {
+		return v, nil
+	}
+	m := partialRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || m[4] != "" {
+		return semver{}, errors.Errorf("could not parse '%s' as a semantic version", s)
+	}
+	var parts [3]uint64
+	for i, p := range m[1:4] {
+		if p == "" {
+			break
+		}
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return semver{}, errors.Errorf("could not parse '%s' as a semantic version", s)
+		}
+		parts[i] = n
+	}
+	return semver{major: parts[0], minor: parts[1], patch: parts[2]}, nil
+}
+
+func (v semver) compare(o semver) int {
+	for _, d := range [][2]uint64{{v.major, o.major}, {v.minor, o.minor}, {v.patch, o.patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		if c := comparePrerelease(v.pre[i], o.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(v.pre), len(o.pre))
+}
+
+func comparePrerelease(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an == bn {
+			return 0
+		} else if an < bn {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type comparator struct {
+	op string
+	v  semver
+}
+
+func (c comparator) matches(v semver) bool {
+	cmp := v.compare(c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// versionConstraint is satisfied by a version that matches every comparator in any one of its alternatives.
+type versionConstraint [][]comparator
+
+func parseConstraint(s string) (versionConstraint, error) {
+	var out versionConstraint
+	for _, alt := range strings.Split(s, "||") {
+		comparators := []comparator{}
+		rest := strings.TrimSpace(alt)
+		if m := hyphenRegex.FindStringSubmatch(rest); m != nil {
+			// a hyphen range includes both bounds; a partial upper bound includes every version it matches
+			lower, err := expandComparator(">=", m[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not parse '%s' as a version constraint", s)
+			}
+			upper, err := expandComparator("<=", m[2])
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not parse '%s' as a version constraint", s)
+			}
+			out = append(out, append(lower, upper...))
+			continue
+		}
+		for rest != "" {
+			m := comparatorRegex.FindStringSubmatch(rest)
+			if m == nil || m[2] == "" {
+				return nil, errors.Errorf("could not parse '%s' as a version constraint", s)
+			}
+			expanded, err := expandComparator(m[1], m[2])
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not parse '%s' as a version constraint", s)
+			}
+			comparators = append(comparators, expanded...)
+			rest = strings.TrimLeft(rest[len(m[0]):], " \t,")
+		}
+		out = append(out, comparators)
+	}
+	return out, nil
+}
+
+// expandComparator converts an operator and a possibly partial version into plain comparators.
+func expandComparator(op, version string) ([]comparator, error) {
+	m := partialRegex.FindStringSubmatch(version)
+	if m == nil {
+		return nil, errors.Errorf("invalid version '%s'", version)
+	}
+	var parts []uint64
+	for _, p := range m[1:4] {
+		if p == "" || p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, _ := strconv.ParseUint(p, 10, 64)
+		parts = append(parts, n)
+	}
+	if m[4] != "" && len(parts) < 3 {
+		return nil, errors.Errorf("invalid version '%s'", version)
+	}
+
+	base := semver{}
+	for i, n := range parts {
+		switch i {
+		case 0:
+			base.major = n
+		case 1:
+			base.minor = n
+		case 2:
+			base.patch = n
+		}
+	}
+	if m[4] != "" {
+		base.pre = strings.Split(m[4], ".")
+	}
+	// next is the smallest version above every version matching the partial version
+	next := func(n int) semver {
+		switch n {
+		case 1:
+			return semver{major: base.major + 1}
+		case 2:
+			return semver{major: base.major, minor: base.minor + 1}
+		}
+		return semver{major: base.major, minor: base.minor, patch: base.patch + 1}
+	}
+	anyVersion := []comparator{}
+	noVersion := []comparator{{op: "<", v: semver{}}}
+
+	n := len(parts)
+	switch op {
+	case "", "=":
+		if n == 0 {
+			return anyVersion, nil
+		} else if n == 3 {
+			return []comparator{{op: "=", v: base}}, nil
+		}
+		return []comparator{{op: ">=", v: base}, {op: "<", v: next(n)}}, nil
+	case ">=":
+		return []comparator{{op: ">=", v: base}}, nil
+	case ">":
+		if n == 0 {
+			return noVersion, nil
+		} else if n == 3 {
+			return []comparator{{op: ">", v: base}}, nil
+		}
+		return []comparator{{op: ">=", v: next(n)}}, nil
+	case "<":
+		if n == 0 {
+			return noVersion, nil
+		}
+		return []comparator{{op: "<", v: base}}, nil
+	case "<=":
+		if n == 0 {
+			return anyVersion, nil
+		} else if n == 3 {
+			return []comparator{{op: "<=", v: base}}, nil
+		}
+		return []comparator{{op: "<", v: next(n)}}, nil
+	case "~", "~>":
+		if n == 0 {
+			return anyVersion, nil
+		} else if n == 1 {
+			return []comparator{{op: ">=", v: base}, {op: "<", v: next(1)}}, nil
+		}
+		return []comparator{{op: ">=", v: base}, {op: "<", v: next(2)}}, nil
+	case "^":
+		switch {
+		case n == 0:
+			return anyVersion, nil
+		case base.major > 0 || n == 1:
+			return []comparator{{op: ">=", v: base}, {op: "<", v: next(1)}}, nil
+		case base.minor > 0 || n == 2:
+			return []comparator{{op: ">=", v: base}, {op: "<", v: next(2)}}, nil
+		}
+		return []comparator{{op: ">=", v: base}, {op: "<", v: next(3)}}, nil
+	}
+	return nil, errors.Errorf("unknown operator '%s'", op)
+}
+
+// hasPrerelease reports whether any comparator of the constraint has a pre-release version.
+func (c versionConstraint) hasPrerelease() bool {
+	for _, alt := range c {
+		for _, comp := range alt {
+			if len(comp.v.pre) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c versionConstraint) matches(v semver) bool {
+	for _, alt := range c {
+		ok := true
+		for _, comp := range alt {
+			if !comp.matches(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package buildpack_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+)
+
+func TestSemver(t *testing.T) {
+	spec.Run(t, "Semver", testSemver, spec.Report(report.Terminal{}))
+}
+
+func testSemver(t *testing.T, when spec.G, it spec.S) {
+	when("Require#SatisfiedBy", func() {
+		satisfied := func(constraint, version string) bool {
+			t.Helper()
+			r := buildpack.Require{Name: "dep", Metadata: map[string]interface{}{"version": constraint}}
+			return r.SatisfiedBy(buildpack.Provide{Name: "dep", Version: version})
+		}
+
+		it("compares versions against constraints", func() {
+			for _, tc := range []struct {
+				constraint, version string
+				expected            bool
+			}{
+				{"1.2.3", "1.2.3", true},
+				{"1.2.3", "1.2.4", false},
+				{"=v1.2.3", "1.2.3", true},
+				{">=16", "16.0.0", true},
+				{">=16", "14.17.0", false},
+				{">16", "16.9.0", false},
+				{">16", "17.0.0", true},
+				{">1.2.3", "1.2.4", true},
+				{"<16", "15.9.9", true},
+				{"<16", "16.0.0", false},
+				{"<=16", "16.9.0", true},
+				{"<=16.1.0", "16.1.1", false},
+				{"14", "14.17.0", true},
+				{"14.x", "15.0.0", false},
+				{"14.17.*", "14.17.3", true},
+				{"14.17.*", "14.18.0", false},
+				{"*", "0.0.1", true},
+				{"~1.2.3", "1.2.9", true},
+				{"~1.2.3", "1.3.0", false},
+				{"~1", "1.9.0", true},
+				{"^1.2.3", "1.9.0", true},
+				{"^1.2.3", "2.0.0", false},
+				{"^0.2.3", "0.2.9", true},
+				{"^0.2.3", "0.3.0", false},
+				{"^0.0.3", "0.0.4", false},
+				{">=1.2.0 <2.0.0", "1.5.0", true},
+				{">=1.2.0, <2.0.0", "2.0.0", false},
+				{">= 1.2.0", "1.2.0", true},
+				{"^14 || ^16", "16.3.0", true},
+				{"^14 || ^16", "15.0.0", false},
+				{">=1.0.0", "1.0.0-rc.1", false},
+				{">=1.0.0-rc.2", "1.0.0-rc.10", true},
+				{">=1.0.0-alpha", "1.0.0-1", false},
+				{"1.2.3", "1.2.3+build.5", true},
+				{">=16", "14", false},
+				{">=16", "14.2", false},
+				{">=16", "16", true},
+				{"^14", "14.2", true},
+				{"1.2.3 - 2.3.4", "1.2.3", true},
+				{"1.2.3 - 2.3.4", "2.3.4", true},
+				{"1.2.3 - 2.3.4", "2.3.5", false},
+				{"1.2.3 - 2.3.4", "1.2.2", false},
+				{"1.2 - 2.3", "2.3.9", true},
+				{"1.2 - 2.3", "2.4.0", false},
+				{"1.2 - 2.3", "1.2.0", true},
+				{"<1.0.0 || 1.2.3 - 2.3.4", "2.0.0", true},
+			} {
+				if actual := satisfied(tc.constraint, tc.version); actual != tc.expected {
+					t.Errorf("Expected '%s' satisfied by '%s' to be %t", tc.constraint, tc.version, tc.expected)
+				}
+			}
+		})
+
+		it("is satisfied when either side has no comparable version", func() {
+			for _, tc := range []struct{ constraint, version string }{
+				{"", "1.2.3"},
+				{">=16", ""},
+				{"latest", "1.2.3"},
+			} {
+				if !satisfied(tc.constraint, tc.version) {
+					t.Errorf("Expected '%s' satisfied by '%s'", tc.constraint, tc.version)
+				}
+			}
+		})
+
+		it("is not satisfied by a provided version that is not a semantic version", func() {
+			for _, tc := range []struct{ constraint, version string }{
+				{">=16", "jdk-11.0.11"},
+				{">=16", "latest"},
+				{">=16", "14.x"},
+			} {
+				if satisfied(tc.constraint, tc.version) {
+					t.Errorf("Expected '%s' not satisfied by '%s'", tc.constraint, tc.version)
+				}
+			}
+		})
+
+		it("uses the deprecated top-level version", func() {
+			r := buildpack.Require{Name: "dep", Version: "^2"}
+			if r.SatisfiedBy(buildpack.Provide{Name: "dep", Version: "1.0.0"}) {
+				t.Fatal("Expected constraint to be unsatisfied")
+			}
+		})
+	})
+}
@@ -0,0 +1,63 @@
+package buildpack
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AnyStack is the stack ID that a buildpack declares to be compatible with every stack.
+const AnyStack = "*"
+
+// Stack is a stack supported by a buildpack, with the mixins the buildpack requires on it.
+// Mixins may be prefixed with "build:" or "run:" when they are only required in one of the stack's images.
+type Stack struct {
+	ID     string   `toml:"id"`
+	Mixins []string `toml:"mixins,omitempty"`
+}
+
+// CheckStack returns an error if the buildpack does not support the stack or requires mixins missing from the
+// build image. An empty stackID or nil buildMixins skip the corresponding check, as do buildpacks that declare no
+// stacks, such as meta-buildpacks.
+func (b *Descriptor) CheckStack(stackID string, buildMixins []string) error {
+	if stackID == "" || len(b.Stacks) == 0 {
+		return nil
+	}
+	var ids []string
+	for _, stack := range b.Stacks {
+		if stack.ID == AnyStack {
+			return nil
+		}
+		if stack.ID != stackID {
+			ids = append(ids, stack.ID)
+			continue
+		}
+		if buildMixins == nil {
+			return nil
+		}
+		if missing := missingMixins(stack.Mixins, buildMixins); len(missing) > 0 {
+			return errors.Errorf("incompatible stack: build image is missing required mixin(s): %s", strings.Join(missing, ", "))
+		}
+		return nil
+	}
+	return errors.Errorf("incompatible stack: '%s' is not one of the supported stacks (%s)", stackID, strings.Join(ids, ", "))
+}
+
+func missingMixins(required, buildMixins []string) []string {
+	available := map[string]bool{}
+	for _, m := range buildMixins {
+		if !strings.HasPrefix(m, "run:") {
+			available[strings.TrimPrefix(m, "build:")] = true
+		}
+	}
+	var missing []string
+	for _, m := range required {
+		if strings.HasPrefix(m, "run:") {
+			continue
+		}
+		if !available[strings.TrimPrefix(m, "build:")] {
+			missing = append(missing, m)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,56 @@
+package buildpack_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestStack(t *testing.T) {
+	spec.Run(t, "Stack", testStack, spec.Report(report.Terminal{}))
+}
+
+func testStack(t *testing.T, when spec.G, it spec.S) {
+	when("#CheckStack", func() {
+		bp := &buildpack.Descriptor{Stacks: []buildpack.Stack{
+			{ID: "io.buildpacks.stacks.bionic", Mixins: []string{"curl", "build:git", "run:tzdata"}},
+			{ID: "io.buildpacks.stacks.focal"},
+		}}
+
+		it("should pass a supported stack", func() {
+			h.AssertNil(t, bp.CheckStack("io.buildpacks.stacks.focal", []string{}))
+		})
+
+		it("should fail an unsupported stack", func() {
+			err := bp.CheckStack("io.buildpacks.stacks.alpine", nil)
+			h.AssertError(t, err, "incompatible stack: 'io.buildpacks.stacks.alpine' is not one of the supported stacks (io.buildpacks.stacks.bionic, io.buildpacks.stacks.focal)")
+		})
+
+		it("should pass any stack when the buildpack supports '*'", func() {
+			anyStack := &buildpack.Descriptor{Stacks: []buildpack.Stack{{ID: "*"}}}
+			h.AssertNil(t, anyStack.CheckStack("io.buildpacks.stacks.alpine", nil))
+		})
+
+		it("should pass when no stacks are declared or the stack is unknown", func() {
+			h.AssertNil(t, (&buildpack.Descriptor{}).CheckStack("io.buildpacks.stacks.alpine", nil))
+			h.AssertNil(t, bp.CheckStack("", nil))
+		})
+
+		it("should ignore run image mixins and stage prefixes", func() {
+			h.AssertNil(t, bp.CheckStack("io.buildpacks.stacks.bionic", []string{"build:curl", "git", "run:jq"}))
+		})
+
+		it("should fail when the build image is missing required mixins", func() {
+			err := bp.CheckStack("io.buildpacks.stacks.bionic", []string{"run:curl"})
+			h.AssertError(t, err, "incompatible stack: build image is missing required mixin(s): curl, build:git")
+		})
+
+		it("should not check mixins when the build image mixins are unknown", func() {
+			h.AssertNil(t, bp.CheckStack("io.buildpacks.stacks.bionic", nil))
+		})
+	})
+}
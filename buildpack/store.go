@@ -1,9 +1,13 @@
 package buildpack
 
 import (
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
 
 	"github.com/buildpacks/lifecycle/launch"
 )
@@ -15,6 +19,67 @@ type Buildpack interface {
 	SupportsAssetPackages() bool
 }
 
+type Store interface {
+	Lookup(bpID, bpVersion string) (Buildpack, error)
+}
+
+// NewStore returns a store for the buildpacks at path: a directory of extracted buildpacks laid out as
+// <id>/<version>, or a buildpackage, either as an OCI image layout directory or a .cnb archive.
+// Buildpacks in a buildpackage are extracted to a private temporary directory when they are first looked up, which
+// CloseStore removes. If path is a list of paths, a CompositeStore searches them in order.
+func NewStore(path string) (Store, error) {
+	if paths := filepath.SplitList(path); len(paths) > 1 {
+		var stores CompositeStore
+		for _, p := range paths {
+			store, err := NewStore(p)
+			if err != nil {
+				return nil, err
+			}
+			stores = append(stores, store)
+		}
+		return stores, nil
+	}
+	if fi, err := os.Stat(path); err == nil && (!fi.IsDir() || isOCILayout(path)) {
+		return NewBuildpackageStore(path, "")
+	}
+	return NewBuildpackStore(path)
+}
+
+// CloseStore releases the resources of a store, such as the buildpacks a BuildpackageStore extracted.
+// The buildpacks it returned must no longer be used.
+func CloseStore(store Store) error {
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// CompositeStore looks up buildpacks in each of its stores in order, returning the first match.
+// A version range is resolved by the first store with a matching version.
+type CompositeStore []Store
+
+func (c CompositeStore) Lookup(bpID, bpVersion string) (Buildpack, error) {
+	var errs []string
+	for _, store := range c {
+		bp, err := store.Lookup(bpID, bpVersion)
+		if err == nil {
+			return bp, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, errors.Errorf("find buildpack '%s@%s': %s", bpID, bpVersion, strings.Join(errs, "; "))
+}
+
+func (c CompositeStore) Close() error {
+	var firstErr error
+	for _, store := range c {
+		if err := CloseStore(store); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 type DirBuildpackStore struct {
 	Dir string
 }
@@ -27,13 +92,84 @@ func NewBuildpackStore(dir string) (*DirBuildpackStore, error) {
 	return &DirBuildpackStore{Dir: dir}, nil
 }
 
+// Lookup returns the buildpack with the given ID and version. If no buildpack is installed with exactly that version,
+// bpVersion may be a semver range, or empty to match any version, and the highest matching installed version is used.
 func (f *DirBuildpackStore) Lookup(bpID, bpVersion string) (Buildpack, error) {
-	bpTOML := Descriptor{}
+	bpVersion, err := f.resolveVersion(bpID, bpVersion)
+	if err != nil {
+		return nil, err
+	}
 	bpPath := filepath.Join(f.Dir, launch.EscapeID(bpID), bpVersion)
-	tomlPath := filepath.Join(bpPath, "buildpack.toml")
-	if _, err := toml.DecodeFile(tomlPath, &bpTOML); err != nil {
+	bpTOML, err := readDescriptor(filepath.Join(bpPath, "buildpack.toml"))
+	if err != nil {
 		return nil, err
 	}
 	bpTOML.Dir = bpPath
+	bpTOML.Source = f.Dir
 	return &bpTOML, nil
 }
+
+func (f *DirBuildpackStore) resolveVersion(bpID, bpVersion string) (string, error) {
+	bpDir := filepath.Join(f.Dir, launch.EscapeID(bpID))
+	if bpVersion != "" {
+		if _, err := os.Stat(filepath.Join(bpDir, bpVersion)); err == nil {
+			return bpVersion, nil
+		}
+		if _, err := parseConstraint(bpVersion); err != nil {
+			return bpVersion, nil // not a range, so it must be an exact version
+		}
+	}
+	fis, err := ioutil.ReadDir(bpDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "list installed versions of buildpack '%s'", bpID)
+	}
+	var installed []string
+	for _, fi := range fis {
+		if fi.IsDir() || fi.Mode()&os.ModeSymlink != 0 {
+			installed = append(installed, fi.Name())
+		}
+	}
+	return ResolveVersion(bpID, bpVersion, installed)
+}
+
+// ResolveVersion returns the installed version matching bpVersion exactly or, if there is none, the highest installed
+// version in the semver range bpVersion. With an empty bpVersion, a single installed version is used even if it
+// is not a semantic version.
+func ResolveVersion(bpID, bpVersion string, installed []string) (string, error) {
+	var constraint versionConstraint
+	if bpVersion != "" {
+		for _, v := range installed {
+			if v == bpVersion {
+				return v, nil
+			}
+		}
+		var err error
+		if constraint, err = parseConstraint(bpVersion); err != nil {
+			return "", errors.Errorf("buildpack '%s@%s' is not installed", bpID, bpVersion)
+		}
+	} else if len(installed) == 1 {
+		return installed[0], nil
+	}
+
+	var best string
+	var bestVersion semver
+	for _, v := range installed {
+		parsed, err := parseSemver(v)
+		if err != nil || (constraint != nil && !constraint.matches(parsed)) {
+			continue
+		}
+		if len(parsed.pre) > 0 && !constraint.hasPrerelease() {
+			continue // pre-releases are only used when the range asks for them
+		}
+		if best == "" || parsed.compare(bestVersion) > 0 {
+			best, bestVersion = v, parsed
+		}
+	}
+	if best == "" {
+		if bpVersion == "" {
+			return "", errors.Errorf("no semantic version of buildpack '%s' is installed", bpID)
+		}
+		return "", errors.Errorf("no installed version of buildpack '%s' matches '%s'", bpID, bpVersion)
+	}
+	return best, nil
+}
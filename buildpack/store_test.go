@@ -0,0 +1,162 @@
+package buildpack_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestStore(t *testing.T) {
+	spec.Run(t, "Store", testStore, spec.Report(report.Terminal{}))
+}
+
+func testStore(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		store  *buildpack.DirBuildpackStore
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "lifecycle.store")
+		h.AssertNil(t, err)
+		store, err = buildpack.NewBuildpackStore(tmpDir)
+		h.AssertNil(t, err)
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	install := func(id string, versions ...string) {
+		t.Helper()
+		for _, v := range versions {
+			bpDir := filepath.Join(tmpDir, strings.ReplaceAll(id, "/", "_"), v)
+			h.Mkdir(t, bpDir)
+			h.Mkfile(t,
+				fmt.Sprintf("api = \"0.6\"\n[buildpack]\nid = \"%s\"\nversion = \"%s\"\n", id, v),
+				filepath.Join(bpDir, "buildpack.toml"),
+			)
+		}
+	}
+
+	lookupVersion := func(id, version string) string {
+		t.Helper()
+		bp, err := store.Lookup(id, version)
+		h.AssertNil(t, err)
+		return bp.ConfigFile().Buildpack.Version
+	}
+
+	when("a path list", func() {
+		var (
+			privateDir string
+			stores     buildpack.Store
+		)
+
+		it.Before(func() {
+			privateDir = filepath.Join(tmpDir, "private")
+			h.Mkdir(t, privateDir)
+			privateStore, err := buildpack.NewBuildpackStore(privateDir)
+			h.AssertNil(t, err)
+
+			for _, bp := range []struct{ dir, id, version string }{
+				{privateDir, "A", "1.0.0"},
+				{privateDir, "B", "1.0.0"},
+				{tmpDir, "B", "1.0.0"},
+				{tmpDir, "C", "2.0.0"},
+			} {
+				bpDir := filepath.Join(bp.dir, bp.id, bp.version)
+				h.Mkdir(t, bpDir)
+				h.Mkfile(t, fmt.Sprintf("[buildpack]\nid = \"%s\"\nversion = \"%s\"\n", bp.id, bp.version), filepath.Join(bpDir, "buildpack.toml"))
+			}
+
+			stores, err = buildpack.NewStore(privateStore.Dir + string(os.PathListSeparator) + store.Dir)
+			h.AssertNil(t, err)
+		})
+
+		it("searches each directory in order", func() {
+			for id, source := range map[string]string{"A": privateDir, "B": privateDir, "C": tmpDir} {
+				bp, err := stores.Lookup(id, "")
+				h.AssertNil(t, err)
+				h.AssertEq(t, bp.ConfigFile().Source, source)
+			}
+		})
+
+		it("fails when no directory has the buildpack", func() {
+			_, err := stores.Lookup("D", "1.0.0")
+			h.AssertError(t, err, "find buildpack 'D@1.0.0'")
+		})
+	})
+
+	when("#Lookup", func() {
+		it("returns the exact version", func() {
+			install("A", "1.0.0", "1.1.0")
+			h.AssertEq(t, lookupVersion("A", "1.0.0"), "1.0.0")
+			bp, err := store.Lookup("A", "1.0.0")
+			h.AssertNil(t, err)
+			h.AssertEq(t, bp.ConfigFile().Dir, filepath.Join(tmpDir, "A", "1.0.0"))
+		})
+
+		it("returns the highest installed version when the version is omitted", func() {
+			install("some/bp", "1.9.0", "1.10.0", "1.2.0", "2.0.0-rc.1")
+			h.AssertEq(t, lookupVersion("some/bp", ""), "1.10.0")
+		})
+
+		it("returns the only installed version when the version is omitted", func() {
+			install("A", "v1")
+			h.AssertEq(t, lookupVersion("A", ""), "v1")
+		})
+
+		it("returns the highest installed version matching a range", func() {
+			install("A", "1.2.0", "1.4.1", "2.0.0")
+			h.AssertEq(t, lookupVersion("A", "^1.2"), "1.4.1")
+			h.AssertEq(t, lookupVersion("A", "~1.2"), "1.2.0")
+			h.AssertEq(t, lookupVersion("A", ">=1.3 <3"), "2.0.0")
+		})
+
+		it("only returns a pre-release when the range includes one", func() {
+			install("A", "1.2.0", "2.0.0-rc.1")
+			h.AssertEq(t, lookupVersion("A", ">=1"), "1.2.0")
+			h.AssertEq(t, lookupVersion("A", ">=2.0.0-rc.0"), "2.0.0-rc.1")
+		})
+
+		it("fails when no installed version matches", func() {
+			install("A", "1.2.0")
+			_, err := store.Lookup("A", "^2")
+			h.AssertError(t, err, "no installed version of buildpack 'A' matches '^2'")
+		})
+
+		it("records the directory the buildpack was found in", func() {
+			install("A", "1.0.0")
+			bp, err := store.Lookup("A", "1.0.0")
+			h.AssertNil(t, err)
+			h.AssertEq(t, bp.ConfigFile().Source, tmpDir)
+		})
+
+		it("fails when detect rules match paths outside the app directory", func() {
+			for _, pattern := range []string{"../secret", "a/../../secret", "/etc/passwd"} {
+				install("A", "1.0.0")
+				h.Mkfile(t,
+					fmt.Sprintf("api = \"0.6\"\n[buildpack]\nid = \"A\"\nversion = \"1.0.0\"\n[detect]\nfiles = [\"%s\"]\n", pattern),
+					filepath.Join(tmpDir, "A", "1.0.0", "buildpack.toml"),
+				)
+				_, err := store.Lookup("A", "1.0.0")
+				h.AssertError(t, err, fmt.Sprintf("detect rule '%s'", pattern))
+			}
+		})
+
+		it("fails when the buildpack is not installed", func() {
+			_, err := store.Lookup("A", "")
+			h.AssertError(t, err, "list installed versions of buildpack 'A'")
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package buildpack
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// LineStream writes the output of concurrently running buildpacks to W as it is produced, prefixing each line with
+// the buildpack ID. Only whole lines are written, so lines from different buildpacks are never interleaved; a line
+// longer than maxLineLength is written in parts.
+type LineStream struct {
+	W io.Writer
+
+	mu sync.Mutex
+}
+
+// Writer returns a writer for the output of one buildpack. Close must be called when the buildpack exits, to write
+// a final line that does not end in a newline.
+func (s *LineStream) Writer(id string) io.WriteCloser {
+	return &prefixWriter{stream: s, prefix: []byte("[" + id + "] ")}
+}
+
+func (s *LineStream) writeLine(prefix, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.W.Write(append(append([]byte{}, prefix...), line...))
+	return err
+}
+
+type prefixWriter struct {
+	stream  *LineStream
+	prefix  []byte
+	partial []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		var line []byte
+		if i := bytes.IndexByte(w.partial, '\n'); i >= 0 && i <= maxLineLength {
+			line = w.partial[:i+1]
+		} else if len(w.partial) >= maxLineLength {
+			line = w.partial[:maxLineLength]
+		} else {
+			return len(p), nil
+		}
+		w.partial = w.partial[len(line):]
+		if line[len(line)-1] != '\n' {
+			line = append(line[:len(line):len(line)], '\n')
+		}
+		if err := w.stream.writeLine(w.prefix, line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (w *prefixWriter) Close() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	line := append(w.partial, '\n')
+	w.partial = nil
+	return w.stream.writeLine(w.prefix, line)
+}
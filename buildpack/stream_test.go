@@ -0,0 +1,84 @@
+package buildpack_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestStream(t *testing.T) {
+	spec.Run(t, "Stream", testStream, spec.Report(report.Terminal{}))
+}
+
+func testStream(t *testing.T, when spec.G, it spec.S) {
+	var (
+		out    *bytes.Buffer
+		stream *buildpack.LineStream
+	)
+
+	it.Before(func() {
+		out = &bytes.Buffer{}
+		stream = &buildpack.LineStream{W: out}
+	})
+
+	it("should prefix each line with the buildpack ID", func() {
+		w := stream.Writer("A")
+		_, err := w.Write([]byte("one\ntw"))
+		h.AssertNil(t, err)
+		h.AssertEq(t, out.String(), "[A] one\n")
+		_, err = w.Write([]byte("o\nthree"))
+		h.AssertNil(t, err)
+		h.AssertNil(t, w.Close())
+		h.AssertEq(t, out.String(), "[A] one\n[A] two\n[A] three\n")
+	})
+
+	it("should write a long line in parts instead of buffering it", func() {
+		w := stream.Writer("A")
+		long := strings.Repeat("x", 5000)
+		_, err := w.Write([]byte(long))
+		h.AssertNil(t, err)
+		h.AssertEq(t, out.String(), "[A] "+long[:4096]+"\n")
+		_, err = w.Write([]byte("\nnext\n"))
+		h.AssertNil(t, err)
+		h.AssertNil(t, w.Close())
+		h.AssertEq(t, out.String(), "[A] "+long[:4096]+"\n[A] "+long[4096:]+"\n[A] next\n")
+	})
+
+	it("should not interleave lines from concurrent writers", func() {
+		var wg sync.WaitGroup
+		for _, id := range []string{"A", "B", "C", "D"} {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				w := stream.Writer(id)
+				for i := 0; i < 200; i++ {
+					line := fmt.Sprintf("%s-line-%d\n", id, i)
+					for _, c := range line { // write a byte at a time to split lines across writes
+						_, err := w.Write([]byte(string(c)))
+						h.AssertNil(t, err)
+					}
+				}
+				h.AssertNil(t, w.Close())
+			}(id)
+		}
+		wg.Wait()
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		h.AssertEq(t, len(lines), 800)
+		lineRegex := regexp.MustCompile(`^\[([A-D])\] ([A-D])-line-\d+$`)
+		for _, line := range lines {
+			if m := lineRegex.FindStringSubmatch(line); m == nil || m[1] != m[2] {
+				t.Fatalf("Unexpected line: %q", line)
+			}
+		}
+	})
+}
@@ -44,6 +44,12 @@ if exist layers-%bp_id%-%bp_version% (
   xcopy /e /q layers-%bp_id%-%bp_version% %layers_dir% >nul
 )
 
+if exist build-sleep (
+  for /f "tokens=* USEBACKQ" %%F in (`type build-sleep`) do (
+    ping -n %%F 127.0.0.1 > nul
+  )
+)
+
 if exist build-status-%bp_id%-%bp_version% (
   for /f "tokens=* USEBACKQ" %%F in (`type build-status-%bp_id%-%bp_version%`) do (
     exit /b %%F
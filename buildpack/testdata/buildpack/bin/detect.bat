@@ -23,6 +23,12 @@ if exist detect-plan-%bp_id%-%bp_version%.toml (
   type detect-plan-%bp_id%-%bp_version%.toml > %plan_path%
 )
 
+if exist detect-sleep (
+  for /f "tokens=* USEBACKQ" %%F in (`type detect-sleep`) do (
+    ping -n %%F 127.0.0.1 > nul
+  )
+)
+
 if exist detect-status-%bp_id%-%bp_version% (
   for /f "tokens=* USEBACKQ" %%F in (`type detect-status-%bp_id%-%bp_version%`) do (
     exit /b %%F
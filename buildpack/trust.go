@@ -0,0 +1,279 @@
+package buildpack
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
+// SignatureFile is the file in a buildpack directory holding the signature of the directory's digest.
+// It is excluded from the digest.
+const SignatureFile = "buildpack.sig"
+
+// TrustPolicy lists the buildpacks that may be executed, identified by the digests of their directories
+// or by a public key that signed those digests.
+type TrustPolicy struct {
+	RejectUnlisted bool         `toml:"reject-unlisted"`
+	Buildpacks     []TrustEntry `toml:"buildpacks"`
+}
+
+type TrustEntry struct {
+	ID        string   `toml:"id"`
+	Version   string   `toml:"version"` // if empty, the entry applies to every version
+	Digests   []string `toml:"digests"`
+	PublicKey string   `toml:"public-key"` // path to a PEM encoded public key, relative to the policy file
+
+	key crypto.PublicKey
+}
+
+// ReadTrustPolicy reads a trust policy and the public keys it references.
+func ReadTrustPolicy(path string) (*TrustPolicy, error) {
+	policy := &TrustPolicy{}
+	if _, err := toml.DecodeFile(path, policy); err != nil {
+		return nil, errors.Wrap(err, "read trust policy")
+	}
+	for i, entry := range policy.Buildpacks {
+		if entry.ID == "" {
+			return nil, errors.Errorf("trust policy entry %d is missing an id", i)
+		}
+		if len(entry.Digests) == 0 && entry.PublicKey == "" {
+			return nil, errors.Errorf("trust policy entry for '%s' must have digests or a public key", entry.ID)
+		}
+		if entry.PublicKey == "" {
+			continue
+		}
+		keyPath := entry.PublicKey
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(filepath.Dir(path), keyPath)
+		}
+		key, err := readPublicKey(keyPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read public key for '%s'", entry.ID)
+		}
+		policy.Buildpacks[i].key = key
+	}
+	return policy, nil
+}
+
+// Verify returns an error unless the buildpack's directory matches a digest or signature in the policy entries for
+// bpID and bpVersion, the ID and resolved version the buildpack was looked up with. The descriptor must have the
+// same ID, so that a buildpack cannot claim the entry of another.
+func (p *TrustPolicy) Verify(bpID, bpVersion string, bp *Descriptor) error {
+	id, version := bpID, bpVersion
+	if bp.Buildpack.ID != id {
+		return errors.Errorf("buildpack '%s@%s' in '%s' is not trusted: it has id '%s'", id, version, bp.Dir, bp.Buildpack.ID)
+	}
+	var entries []TrustEntry
+	for _, entry := range p.Buildpacks {
+		if entry.ID == id && (entry.Version == "" || entry.Version == version) {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		if p.RejectUnlisted {
+			return errors.Errorf("buildpack '%s@%s' is not listed in the trust policy", id, version)
+		}
+		return nil
+	}
+
+	digest, err := DirDigest(bp.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "compute digest of buildpack '%s@%s'", id, version)
+	}
+	for _, entry := range entries {
+		for _, d := range entry.Digests {
+			if d == digest {
+				return nil
+			}
+		}
+	}
+	signature, err := readSignature(bp.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "read signature of buildpack '%s@%s'", id, version)
+	}
+	for _, entry := range entries {
+		if entry.key != nil && signature != nil && verifySignature(entry.key, []byte(digest), signature) {
+			return nil
+		}
+	}
+	if signature == nil && entries[0].key != nil {
+		return errors.Errorf("buildpack '%s@%s' in '%s' is not trusted: digest %s is not in the trust policy and %s is missing", id, version, bp.Dir, digest, SignatureFile)
+	}
+	return errors.Errorf("buildpack '%s@%s' in '%s' is not trusted: digest %s does not match the trust policy", id, version, bp.Dir, digest)
+}
+
+// TrustedStore verifies each buildpack it looks up against a trust policy, so that untrusted buildpacks are never
+// executed. A buildpack directory is verified once, the first time it is looked up.
+type TrustedStore struct {
+	Store
+	Policy *TrustPolicy
+
+	mu       sync.Mutex
+	verified map[string]bool // <id>@<version> and directory of each verified buildpack
+}
+
+func (s *TrustedStore) Close() error {
+	return CloseStore(s.Store)
+}
+
+func (s *TrustedStore) Lookup(bpID, bpVersion string) (Buildpack, error) {
+	bp, err := s.Store.Lookup(bpID, bpVersion)
+	if err != nil {
+		return nil, err
+	}
+	version, err := resolvedVersion(bpID, bpVersion, bp.ConfigFile())
+	if err != nil {
+		return nil, NewLifecycleError(err, ErrTypeUntrusted)
+	}
+	key := bpID + "@" + version + "\x00" + bp.ConfigFile().Dir
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.verified[key] {
+		return bp, nil
+	}
+	if err := s.Policy.Verify(bpID, version, bp.ConfigFile()); err != nil {
+		return nil, NewLifecycleError(err, ErrTypeUntrusted)
+	}
+	if s.verified == nil {
+		s.verified = map[string]bool{}
+	}
+	s.verified[key] = true
+	return bp, nil
+}
+
+// resolvedVersion returns the version the store resolved bpVersion to, which is the version of the buildpack it
+// returned if that is bpVersion or in the range bpVersion.
+func resolvedVersion(bpID, bpVersion string, bp *Descriptor) (string, error) {
+	version := bp.Buildpack.Version
+	if bpVersion == "" || bpVersion == version {
+		return version, nil
+	}
+	if constraint, err := parseConstraint(bpVersion); err == nil {
+		if parsed, err := parseSemver(version); err == nil && constraint.matches(parsed) {
+			return version, nil
+		}
+	}
+	return "", errors.Errorf("buildpack '%s@%s' in '%s' is not trusted: it has version '%s'", bpID, bpVersion, bp.Dir, version)
+}
+
+// DirDigest returns the digest of a buildpack directory. It covers the path, type and contents of every file,
+// the executable bit of regular files and the targets of symlinks, but not ownership, timestamps or the
+// signature file. It fails for a symlink that does not resolve to a path within the directory, as the file it
+// resolves to would not be covered.
+func DirDigest(dir string) (string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." || rel == SignatureFile {
+			return nil
+		}
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return errors.Wrapf(err, "resolve symlink '%s'", rel)
+			}
+			if !isWithin(root, resolved) {
+				return errors.Errorf("symlink '%s' resolves to '%s' outside the buildpack", rel, resolved)
+			}
+			fmt.Fprintf(h, "%s\x00l\x00%s\x00", rel, filepath.ToSlash(target))
+		case fi.IsDir():
+			fmt.Fprintf(h, "%s\x00d\x00", rel)
+		case fi.Mode().IsRegular():
+			fileType := "f"
+			if fi.Mode()&0111 != 0 {
+				fileType = "x"
+			}
+			fmt.Fprintf(h, "%s\x00%s\x00%d\x00", rel, fileType, fi.Size())
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readSignature(dir string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, SignatureFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(contents)))
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data in '%s'", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, errors.Errorf("unsupported public key type %T", key)
+}
+
+// verifySignature verifies a signature of the payload: a SHA-256 ECDSA (ASN.1) or RSA PKCS #1 v1.5 signature,
+// or an ed25519 signature of the payload itself.
+func verifySignature(key crypto.PublicKey, payload, signature []byte) bool {
+	sum := sha256.Sum256(payload)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, sum[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	}
+	return false
+}
@@ -0,0 +1,274 @@
+package buildpack_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestTrust(t *testing.T) {
+	spec.Run(t, "Trust", testTrust, spec.Report(report.Terminal{}))
+}
+
+func testTrust(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		bpDir  string
+		store  *buildpack.DirBuildpackStore
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "lifecycle.trust")
+		h.AssertNil(t, err)
+		bpDir = filepath.Join(tmpDir, "buildpacks", "A", "v1")
+		h.Mkdir(t, filepath.Join(bpDir, "bin"))
+		h.Mkfile(t, "api = \"0.6\"\n[buildpack]\nid = \"A\"\nversion = \"v1\"\n", filepath.Join(bpDir, "buildpack.toml"))
+		h.Mkfile(t, "#!/bin/sh\n", filepath.Join(bpDir, "bin", "detect"), filepath.Join(bpDir, "bin", "build"))
+		h.AssertNil(t, os.Chmod(filepath.Join(bpDir, "bin", "build"), 0755))
+		store, err = buildpack.NewBuildpackStore(filepath.Join(tmpDir, "buildpacks"))
+		h.AssertNil(t, err)
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	digest := func() string {
+		t.Helper()
+		d, err := buildpack.DirDigest(bpDir)
+		h.AssertNil(t, err)
+		return d
+	}
+
+	writePolicy := func(contents string) *buildpack.TrustPolicy {
+		t.Helper()
+		path := filepath.Join(tmpDir, "policy", "trust.toml")
+		h.Mkdir(t, filepath.Dir(path))
+		h.Mkfile(t, contents, path)
+		policy, err := buildpack.ReadTrustPolicy(path)
+		h.AssertNil(t, err)
+		return policy
+	}
+
+	lookup := func(policy *buildpack.TrustPolicy) error {
+		t.Helper()
+		_, err := (&buildpack.TrustedStore{Store: store, Policy: policy}).Lookup("A", "v1")
+		return err
+	}
+
+	when("#DirDigest", func() {
+		it("should be stable", func() {
+			d := digest()
+			h.AssertEq(t, strings.HasPrefix(d, "sha256:"), true)
+			h.AssertEq(t, digest(), d)
+		})
+
+		it("should ignore the signature file", func() {
+			d := digest()
+			h.Mkfile(t, "c2ln", filepath.Join(bpDir, buildpack.SignatureFile))
+			h.AssertEq(t, digest(), d)
+		})
+
+		it("should change when a file changes", func() {
+			d := digest()
+			h.Mkfile(t, "#!/bin/sh\nexit 1\n", filepath.Join(bpDir, "bin", "detect"))
+			if digest() == d {
+				t.Fatal("expected digest to change")
+			}
+		})
+
+		it("should fail for a symlink that resolves outside the buildpack", func() {
+			if runtime.GOOS == "windows" {
+				t.Skip("creating symlinks requires privileges on Windows")
+			}
+			outside := filepath.Join(tmpDir, "outside")
+			h.Mkfile(t, "#!/bin/sh\n", outside)
+			h.AssertNil(t, os.Remove(filepath.Join(bpDir, "bin", "build")))
+			h.AssertNil(t, os.Symlink(outside, filepath.Join(bpDir, "bin", "build")))
+
+			_, err := buildpack.DirDigest(bpDir)
+			h.AssertError(t, err, "symlink 'bin/build' resolves to")
+		})
+
+		it("should include symlinks within the buildpack", func() {
+			if runtime.GOOS == "windows" {
+				t.Skip("creating symlinks requires privileges on Windows")
+			}
+			d := digest()
+			h.AssertNil(t, os.Symlink("detect", filepath.Join(bpDir, "bin", "other")))
+			if digest() == d {
+				t.Fatal("expected digest to change")
+			}
+		})
+
+		it("should change when a file becomes executable", func() {
+			d := digest()
+			h.AssertNil(t, os.Chmod(filepath.Join(bpDir, "bin", "detect"), 0755))
+			if digest() == d {
+				t.Fatal("expected digest to change")
+			}
+		})
+	})
+
+	when("the policy lists digests", func() {
+		it("should allow a buildpack with a listed digest", func() {
+			policy := writePolicy(fmt.Sprintf("[[buildpacks]]\nid = \"A\"\nversion = \"v1\"\ndigests = [\"sha256:other\", \"%s\"]\n", digest()))
+			h.AssertNil(t, lookup(policy))
+		})
+
+		it("should reject a modified buildpack", func() {
+			policy := writePolicy(fmt.Sprintf("[[buildpacks]]\nid = \"A\"\ndigests = [\"%s\"]\n", digest()))
+			h.Mkfile(t, "#!/bin/sh\nexit 1\n", filepath.Join(bpDir, "bin", "detect"))
+
+			err := lookup(policy)
+			h.AssertNotNil(t, err)
+			bpErr, ok := err.(*buildpack.Error)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, bpErr.Type, buildpack.ErrTypeUntrusted)
+			h.AssertStringContains(t, err.Error(), fmt.Sprintf("buildpack 'A@v1' in '%s' is not trusted: digest %s does not match the trust policy", bpDir, digest()))
+		})
+
+		it("should allow a buildpack with a listed digest and a malformed signature", func() {
+			h.Mkfile(t, "not base64!\n", filepath.Join(bpDir, buildpack.SignatureFile))
+			policy := writePolicy(fmt.Sprintf("[[buildpacks]]\nid = \"A\"\ndigests = [\"%s\"]\n", digest()))
+			h.AssertNil(t, lookup(policy))
+		})
+
+		it("should reject a buildpack whose descriptor has another id", func() {
+			h.Mkfile(t, "api = \"0.6\"\n[buildpack]\nid = \"B\"\nversion = \"v1\"\n", filepath.Join(bpDir, "buildpack.toml"))
+			policy := writePolicy(fmt.Sprintf("[[buildpacks]]\nid = \"B\"\ndigests = [\"%s\"]\n", digest()))
+
+			err := lookup(policy)
+			h.AssertNotNil(t, err)
+			h.AssertStringContains(t, err.Error(), fmt.Sprintf("buildpack 'A@v1' in '%s' is not trusted: it has id 'B'", bpDir))
+		})
+
+		it("should reject a buildpack whose descriptor has another version", func() {
+			h.Mkfile(t, "api = \"0.6\"\n[buildpack]\nid = \"A\"\nversion = \"v2\"\n", filepath.Join(bpDir, "buildpack.toml"))
+			policy := writePolicy(fmt.Sprintf("[[buildpacks]]\nid = \"A\"\nversion = \"v2\"\ndigests = [\"%s\"]\n", digest()))
+
+			err := lookup(policy)
+			h.AssertNotNil(t, err)
+			h.AssertStringContains(t, err.Error(), fmt.Sprintf("buildpack 'A@v1' in '%s' is not trusted: it has version 'v2'", bpDir))
+		})
+
+		it("should use the entries for the version a range resolves to", func() {
+			semverDir := filepath.Join(tmpDir, "buildpacks", "A", "1.2.0")
+			h.Mkdir(t, semverDir)
+			h.Mkfile(t, "api = \"0.6\"\n[buildpack]\nid = \"A\"\nversion = \"1.2.0\"\n", filepath.Join(semverDir, "buildpack.toml"))
+			h.AssertNil(t, os.RemoveAll(bpDir))
+			policy := writePolicy("reject-unlisted = true\n[[buildpacks]]\nid = \"A\"\nversion = \"1.2.0\"\ndigests = [\"sha256:other\"]\n")
+
+			_, err := (&buildpack.TrustedStore{Store: store, Policy: policy}).Lookup("A", "^1.0")
+			h.AssertNotNil(t, err)
+			h.AssertStringContains(t, err.Error(), "buildpack 'A@1.2.0' in '"+semverDir+"' is not trusted: digest")
+		})
+
+		it("should verify each buildpack directory once", func() {
+			policy := writePolicy(fmt.Sprintf("[[buildpacks]]\nid = \"A\"\ndigests = [\"%s\"]\n", digest()))
+			trusted := &buildpack.TrustedStore{Store: store, Policy: policy}
+			_, err := trusted.Lookup("A", "v1")
+			h.AssertNil(t, err)
+			h.Mkfile(t, "changed", filepath.Join(bpDir, "bin", "detect"))
+
+			_, err = trusted.Lookup("A", "v1")
+			h.AssertNil(t, err)
+		})
+
+		it("should ignore entries for other versions", func() {
+			policy := writePolicy("[[buildpacks]]\nid = \"A\"\nversion = \"v2\"\ndigests = [\"sha256:other\"]\n")
+			h.AssertNil(t, lookup(policy))
+		})
+	})
+
+	when("reject-unlisted is set", func() {
+		it("should reject buildpacks that are not in the policy", func() {
+			policy := writePolicy("reject-unlisted = true\n[[buildpacks]]\nid = \"B\"\ndigests = [\"sha256:other\"]\n")
+			err := lookup(policy)
+			h.AssertNotNil(t, err)
+			h.AssertStringContains(t, err.Error(), "buildpack 'A@v1' is not listed in the trust policy")
+		})
+	})
+
+	when("the policy lists a public key", func() {
+		var key *ecdsa.PrivateKey
+
+		it.Before(func() {
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			h.AssertNil(t, err)
+			der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+			h.AssertNil(t, err)
+			h.Mkdir(t, filepath.Join(tmpDir, "policy", "keys"))
+			h.Mkfile(t,
+				string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+				filepath.Join(tmpDir, "policy", "keys", "a.pem"),
+			)
+		})
+
+		sign := func() {
+			t.Helper()
+			sum := sha256.Sum256([]byte(digest()))
+			sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+			h.AssertNil(t, err)
+			h.Mkfile(t, base64.StdEncoding.EncodeToString(sig)+"\n", filepath.Join(bpDir, buildpack.SignatureFile))
+		}
+
+		it("should allow a signed buildpack", func() {
+			sign()
+			policy := writePolicy("[[buildpacks]]\nid = \"A\"\npublic-key = \"keys/a.pem\"\n")
+			h.AssertNil(t, lookup(policy))
+		})
+
+		it("should reject a buildpack modified after signing", func() {
+			sign()
+			policy := writePolicy("[[buildpacks]]\nid = \"A\"\npublic-key = \"keys/a.pem\"\n")
+			h.Mkfile(t, "#!/bin/sh\nexit 1\n", filepath.Join(bpDir, "bin", "detect"))
+
+			err := lookup(policy)
+			h.AssertNotNil(t, err)
+			h.AssertStringContains(t, err.Error(), "is not trusted")
+		})
+
+		it("should reject an unsigned buildpack", func() {
+			policy := writePolicy("[[buildpacks]]\nid = \"A\"\npublic-key = \"keys/a.pem\"\n")
+			err := lookup(policy)
+			h.AssertNotNil(t, err)
+			h.AssertStringContains(t, err.Error(), buildpack.SignatureFile+" is missing")
+		})
+	})
+
+	when("#ReadTrustPolicy", func() {
+		it("should fail for entries without digests or a public key", func() {
+			path := filepath.Join(tmpDir, "trust.toml")
+			h.Mkfile(t, "[[buildpacks]]\nid = \"A\"\n", path)
+			_, err := buildpack.ReadTrustPolicy(path)
+			h.AssertError(t, err, "trust policy entry for 'A' must have digests or a public key")
+		})
+
+		it("should fail for a missing public key", func() {
+			path := filepath.Join(tmpDir, "trust.toml")
+			h.Mkfile(t, "[[buildpacks]]\nid = \"A\"\npublic-key = \"missing.pem\"\n", path)
+			_, err := buildpack.ReadTrustPolicy(path)
+			h.AssertError(t, err, "read public key for 'A'")
+		})
+	})
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 // Command defines the interface for running the lifecycle phases
@@ -60,3 +63,8 @@ func Run(c Command, asSubcommand bool) {
 	}
 	Exit(c.Exec())
 }
+
+// InterruptContext returns a context that is canceled when the lifecycle receives SIGINT or SIGTERM.
+func InterruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -33,6 +33,8 @@ const (
 	ExportError                               // generic export error
 	RebaseError                               // generic rebase error
 	LaunchError                               // generic launch error
+	DetectInterrupted                         // detect phase interrupted by a signal
+	BuildInterrupted                          // build phase interrupted by a signal
 )
 
 type Platform interface {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 
 	"github.com/buildpacks/lifecycle/api"
@@ -12,6 +13,7 @@ import (
 
 var (
 	DefaultAppDir          = filepath.Join(rootDir, "workspace")
+	DefaultBuildLogTail    = 20
 	DefaultBuildpacksDir   = filepath.Join(rootDir, "cnb", "buildpacks")
 	DefaultDeprecationMode = DeprecationModeWarn
 	DefaultLauncherPath    = filepath.Join(rootDir, "cnb", "lifecycle", "launcher"+execExt)
@@ -23,13 +25,20 @@ var (
 	DefaultStackPath       = filepath.Join(rootDir, "cnb", "stack.toml")
 
 	DefaultAnalyzedFile        = "analyzed.toml"
+	DefaultBuildCheckpointFile = "build-checkpoint.toml"
+	DefaultBuildReportFile     = "build-report.toml"
+	DefaultDetectReportFile    = "detect-report.toml"
 	DefaultGroupFile           = "group.toml"
 	DefaultOrderFile           = "order.toml"
 	DefaultPlanFile            = "plan.toml"
 	DefaultProjectMetadataFile = "project-metadata.toml"
 	DefaultReportFile          = "report.toml"
+	DefaultRootDirsFile        = "root-dirs.toml"
 
 	PlaceholderAnalyzedPath        = filepath.Join("<layers>", DefaultAnalyzedFile)
+	PlaceholderBuildCheckpointPath = filepath.Join("<layers>", DefaultBuildCheckpointFile)
+	PlaceholderBuildReportPath     = filepath.Join("<layers>", DefaultBuildReportFile)
+	PlaceholderDetectReportPath    = filepath.Join("<layers>", DefaultDetectReportFile)
 	PlaceholderGroupPath           = filepath.Join("<layers>", DefaultGroupFile)
 	PlaceholderPlanPath            = filepath.Join("<layers>", DefaultPlanFile)
 	PlaceholderProjectMetadataPath = filepath.Join("<layers>", DefaultProjectMetadataFile)
@@ -41,15 +50,32 @@ const (
 	EnvAnalyzedPath        = "CNB_ANALYZED_PATH"
 	EnvAppDir              = "CNB_APP_DIR"
 	EnvBuildpacksDir       = "CNB_BUILDPACKS_DIR"
+	EnvBuildCheckpointPath = "CNB_BUILD_CHECKPOINT_PATH"
+	EnvBuildEnvReportPath  = "CNB_BUILD_ENV_REPORT_PATH"
+	EnvBuildLogsDir        = "CNB_BUILD_LOGS_DIR"
+	EnvBuildLogTail        = "CNB_BUILD_LOG_TAIL"
+	EnvBuildMixins         = "CNB_BUILD_MIXINS"
+	EnvBuildReportPath     = "CNB_BUILD_REPORT_PATH"
+	EnvBuildResume         = "CNB_BUILD_RESUME" // defaults to false
+	EnvBuildTimeout        = "CNB_BUILD_TIMEOUT"
 	EnvCacheDir            = "CNB_CACHE_DIR"
 	EnvCacheImage          = "CNB_CACHE_IMAGE"
 	EnvDeprecationMode     = "CNB_DEPRECATION_MODE"
+	EnvDetectCacheDir      = "CNB_DETECT_CACHE_DIR"
+	EnvDetectConcurrency   = "CNB_DETECT_CONCURRENCY"
+	EnvDetectReportPath    = "CNB_DETECT_REPORT_PATH"
+	EnvDetectSimulation    = "CNB_DETECT_SIMULATION"
+	EnvDetectSpeculate     = "CNB_DETECT_SPECULATE"
+	EnvDetectStream        = "CNB_DETECT_STREAM" // defaults to false
+	EnvDetectTimeout       = "CNB_DETECT_TIMEOUT"
 	EnvGID                 = "CNB_GROUP_ID"
 	EnvGroupPath           = "CNB_GROUP_PATH"
 	EnvLaunchCacheDir      = "CNB_LAUNCH_CACHE_DIR"
 	EnvLayersDir           = "CNB_LAYERS_DIR"
 	EnvLogLevel            = "CNB_LOG_LEVEL"
 	EnvNoColor             = "CNB_NO_COLOR" // defaults to false
+	EnvOffline             = "CNB_OFFLINE"  // defaults to false
+	EnvOrderGroup          = "CNB_ORDER_GROUP"
 	EnvOrderPath           = "CNB_ORDER_PATH"
 	EnvPlanPath            = "CNB_PLAN_PATH"
 	EnvPlatformAPI         = "CNB_PLATFORM_API"
@@ -58,10 +84,13 @@ const (
 	EnvProcessType         = "CNB_PROCESS_TYPE"
 	EnvProjectMetadataPath = "CNB_PROJECT_METADATA_PATH"
 	EnvReportPath          = "CNB_REPORT_PATH"
+	EnvRootDirsPath        = "CNB_ROOT_DIRS_PATH"
 	EnvRunImage            = "CNB_RUN_IMAGE"
 	EnvSkipLayers          = "CNB_ANALYZE_SKIP_LAYERS" // defaults to false
 	EnvSkipRestore         = "CNB_SKIP_RESTORE"        // defaults to false
+	EnvStackID             = "CNB_STACK_ID"
 	EnvStackPath           = "CNB_STACK_PATH"
+	EnvTrustPolicyPath     = "CNB_TRUST_POLICY_PATH"
 	EnvUID                 = "CNB_USER_ID"
 	EnvUseDaemon           = "CNB_USE_DAEMON" // defaults to false
 )
@@ -81,7 +110,47 @@ func FlagAppDir(appDir *string) {
 }
 
 func FlagBuildpacksDir(buildpacksDir *string) {
-	flagSet.StringVar(buildpacksDir, "buildpacks", EnvOrDefault(EnvBuildpacksDir, DefaultBuildpacksDir), "path to buildpacks directory")
+	flagSet.StringVar(buildpacksDir, "buildpacks", EnvOrDefault(EnvBuildpacksDir, DefaultBuildpacksDir), "path to buildpacks directory, OCI image layout or .cnb buildpackage, or a list of them to search in order")
+}
+
+func FlagBuildCheckpointPath(checkpointPath *string) {
+	flagSet.StringVar(checkpointPath, "build-checkpoint", EnvOrDefault(EnvBuildCheckpointPath, PlaceholderBuildCheckpointPath), "path to save build progress to after each buildpack succeeds")
+}
+
+func DefaultBuildCheckpointPath(platformAPI, layersDir string) string {
+	return defaultPath(DefaultBuildCheckpointFile, platformAPI, layersDir)
+}
+
+func FlagBuildEnvReportPath(envReportPath *string) {
+	flagSet.StringVar(envReportPath, "build-env-report", os.Getenv(EnvBuildEnvReportPath), "path to write how each variable of each buildpack's build env got its value to")
+}
+
+func FlagBuildLogsDir(logsDir *string) {
+	flagSet.StringVar(logsDir, "build-logs", os.Getenv(EnvBuildLogsDir), "path to a directory to write the output of each buildpack's bin/build to")
+}
+
+func FlagBuildLogTail(lines *int) {
+	flagSet.IntVar(lines, "build-log-tail", intEnvOrDefault(EnvBuildLogTail, DefaultBuildLogTail), "number of lines of a failed buildpack's output to include in the error")
+}
+
+func FlagBuildMixins(buildMixins *string) {
+	flagSet.StringVar(buildMixins, "build-mixins", os.Getenv(EnvBuildMixins), "mixins of the build image, as a comma-separated list or the JSON value of its io.buildpacks.stack.mixins label")
+}
+
+func FlagBuildReportPath(buildReportPath *string) {
+	flagSet.StringVar(buildReportPath, "build-report", EnvOrDefault(EnvBuildReportPath, PlaceholderBuildReportPath), "path to build-report.toml")
+}
+
+func DefaultBuildReportPath(platformAPI, layersDir string) string {
+	return defaultPath(DefaultBuildReportFile, platformAPI, layersDir)
+}
+
+func FlagBuildResume(resume *bool) {
+	flagSet.BoolVar(resume, "resume", BoolEnv(EnvBuildResume), "continue a failed build from the first buildpack that did not complete, using the build checkpoint")
+}
+
+func FlagBuildTimeout(buildTimeout *string) {
+	flagSet.StringVar(buildTimeout, "build-timeout", os.Getenv(EnvBuildTimeout), "timeout for each buildpack's bin/build, e.g. '30m' or '30m,<buildpack-id>=1h'")
 }
 
 func FlagCacheDir(cacheDir *string) {
@@ -92,6 +161,38 @@ func FlagCacheImage(cacheImage *string) {
 	flagSet.StringVar(cacheImage, "cache-image", os.Getenv(EnvCacheImage), "cache image tag name")
 }
 
+func FlagDetectCacheDir(detectCacheDir *string) {
+	flagSet.StringVar(detectCacheDir, "detect-cache", os.Getenv(EnvDetectCacheDir), "path to a directory for caching detect results across builds")
+}
+
+func FlagDetectConcurrency(concurrency *int) {
+	flagSet.IntVar(concurrency, "detect-concurrency", intEnvOrDefault(EnvDetectConcurrency, runtime.NumCPU()), "maximum number of buildpacks to detect at once (0 for no limit)")
+}
+
+func FlagDetectReportPath(detectReportPath *string) {
+	flagSet.StringVar(detectReportPath, "detect-report", EnvOrDefault(EnvDetectReportPath, PlaceholderDetectReportPath), "path to detect-report.toml (written as JSON if the path ends in .json)")
+}
+
+func DefaultDetectReportPath(platformAPI, layersDir string) string {
+	return defaultPath(DefaultDetectReportFile, platformAPI, layersDir)
+}
+
+func FlagDetectSimulation(simulationPath *string) {
+	flagSet.StringVar(simulationPath, "simulate", os.Getenv(EnvDetectSimulation), "path to recorded detect results to resolve the order against instead of running buildpacks; the group and plan are printed and no files are written")
+}
+
+func FlagDetectSpeculate(speculate *int) {
+	flagSet.IntVar(speculate, "detect-speculate", intEnv(EnvDetectSpeculate), "number of detect runs to start early for buildpacks in later order groups (0 disables)")
+}
+
+func FlagDetectStream(stream *bool) {
+	flagSet.BoolVar(stream, "stream-detect", BoolEnv(EnvDetectStream), "print the output of each buildpack's bin/detect as it runs, prefixed with the buildpack ID")
+}
+
+func FlagDetectTimeout(detectTimeout *string) {
+	flagSet.StringVar(detectTimeout, "detect-timeout", os.Getenv(EnvDetectTimeout), "timeout for each buildpack's bin/detect, e.g. '1m' or '1m,<buildpack-id>=5m'")
+}
+
 func FlagGID(gid *int) {
 	flagSet.IntVar(gid, "gid", intEnv(EnvGID), "GID of user's group in the stack's build and run images")
 }
@@ -120,6 +221,14 @@ func FlagNoColor(skip *bool) {
 	flagSet.BoolVar(skip, "no-color", BoolEnv(EnvNoColor), "disable color output")
 }
 
+func FlagOffline(offline *bool) {
+	flagSet.BoolVar(offline, "offline", BoolEnv(EnvOffline), "run buildpacks in a network namespace with only a loopback interface, so that they cannot download anything (Linux only)")
+}
+
+func FlagOrderGroup(orderGroup *string) {
+	flagSet.StringVar(orderGroup, "order-group", os.Getenv(EnvOrderGroup), "only detect one group of the order, given by its 1-based index or the ID of its first buildpack")
+}
+
 func FlagOrderPath(orderPath *string) {
 	flagSet.StringVar(orderPath, "order", EnvOrDefault(EnvOrderPath, PlaceholderOrderPath), "path to order.toml")
 }
@@ -164,6 +273,10 @@ func DefaultReportPath(platformAPI, layersDir string) string {
 	return defaultPath(DefaultReportFile, platformAPI, layersDir)
 }
 
+func FlagRootDirsPath(rootDirsPath *string) {
+	flagSet.StringVar(rootDirsPath, "root-dirs", os.Getenv(EnvRootDirsPath), "path to root-dirs.toml, which extends the layer directories added to build and launch env vars (default <platform>/root-dirs.toml, then <lifecycle>/root-dirs.toml, if present)")
+}
+
 func FlagRunImage(runImage *string) {
 	flagSet.StringVar(runImage, "run-image", os.Getenv(EnvRunImage), "reference to run image")
 }
@@ -176,6 +289,10 @@ func FlagSkipRestore(skip *bool) {
 	flagSet.BoolVar(skip, "skip-restore", BoolEnv(EnvSkipRestore), "do not restore layers or layer metadata")
 }
 
+func FlagStackID(stackID *string) {
+	flagSet.StringVar(stackID, "stack-id", os.Getenv(EnvStackID), "ID of the stack; buildpacks that do not support it fail detection")
+}
+
 func FlagStackPath(stackPath *string) {
 	flagSet.StringVar(stackPath, "stack", EnvOrDefault(EnvStackPath, DefaultStackPath), "path to stack.toml")
 }
@@ -184,6 +301,10 @@ func FlagTags(tags *StringSlice) {
 	flagSet.Var(tags, "tag", "additional tags")
 }
 
+func FlagTrustPolicyPath(trustPolicyPath *string) {
+	flagSet.StringVar(trustPolicyPath, "trust-policy", os.Getenv(EnvTrustPolicyPath), "path to a trust policy listing the digests or public keys of buildpacks that may be executed")
+}
+
 func FlagUID(uid *int) {
 	flagSet.IntVar(uid, "uid", intEnv(EnvUID), "UID of user in the stack's build and run images")
 }
@@ -236,6 +357,13 @@ func intEnv(k string) int {
 	return d
 }
 
+func intEnvOrDefault(k string, defaultVal int) int {
+	if _, ok := os.LookupEnv(k); !ok {
+		return defaultVal
+	}
+	return intEnv(k)
+}
+
 func BoolEnv(k string) bool {
 	v := os.Getenv(k)
 	b, err := strconv.ParseBool(v)
@@ -15,6 +15,9 @@ import (
 	lplatform "github.com/buildpacks/lifecycle/platform"
 )
 
+// explainEnvArg makes the launcher print how the env of the process was produced instead of launching it.
+const explainEnvArg = "--explain-env"
+
 func main() {
 	cmd.Exit(runLaunch())
 }
@@ -42,6 +45,10 @@ func runLaunch() error {
 
 	defaultProcessType := defaultProcessType(api.MustParse(platform.API()), md)
 
+	args := os.Args[1:]
+	explainEnv := len(args) > 0 && args[0] == explainEnvArg
+	launchEnv := env.NewLaunchEnv(os.Environ(), launch.ProcessDir, launch.LifecycleDir)
+	launchEnv.RootDirMap = env.ExtendRootDirMap(env.POSIXLaunchEnv, md.RootDirs)
 	launcher := &launch.Launcher{
 		DefaultProcessType: defaultProcessType,
 		LayersDir:          cmd.EnvOrDefault(cmd.EnvLayersDir, cmd.DefaultLayersDir),
@@ -49,14 +56,24 @@ func runLaunch() error {
 		PlatformAPI:        api.MustParse(platform.API()),
 		Processes:          md.Processes,
 		Buildpacks:         md.Buildpacks,
-		Env:                env.NewLaunchEnv(os.Environ(), launch.ProcessDir, launch.LifecycleDir),
+		Env:                launchEnv,
 		Exec:               launch.OSExecFunc,
 		ExecD:              launch.NewExecDRunner(),
 		Shell:              launch.DefaultShell,
 		Setenv:             os.Setenv,
 	}
 
-	if err := launcher.Launch(os.Args[0], os.Args[1:]); err != nil {
+	if explainEnv {
+		launchEnv.Trace = &env.Trace{}
+		launcher.Trace = launchEnv.Trace
+		launcher.ExecD = &launch.ExecDRunner{Out: os.Stderr, Err: os.Stderr} // keep stdout for the explanation
+		if err := launcher.ExplainEnv(args[1:], os.Stdout); err != nil {
+			return cmd.FailErrCode(err, platform.CodeFor(cmd.LaunchError), "explain env")
+		}
+		return nil
+	}
+
+	if err := launcher.Launch(os.Args[0], args); err != nil {
 		return cmd.FailErrCode(err, platform.CodeFor(cmd.LaunchError), "launch")
 	}
 	return nil
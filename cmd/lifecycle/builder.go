@@ -9,6 +9,7 @@ import (
 	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/env"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/priv"
@@ -16,17 +17,30 @@ import (
 
 type buildCmd struct {
 	// flags: inputs
-	groupPath string
-	planPath  string
+	groupPath       string
+	planPath        string
+	buildTimeout    string
+	rootDirsPath    string
+	trustPolicyPath string
 	buildArgs
 }
 
 type buildArgs struct {
 	// inputs needed when run by creator
-	buildpacksDir string
-	layersDir     string
-	appDir        string
-	platformDir   string
+	buildpacksDir   string
+	layersDir       string
+	appDir          string
+	checkpointPath  string
+	envReportPath   string
+	buildLogsDir    string
+	buildLogTail    int
+	buildReportPath string
+	platformDir     string
+	offline         bool
+	resume          bool
+	rootDirs        env.RootDirs
+	timeouts        buildpack.Timeouts
+	trustPolicy     *buildpack.TrustPolicy
 
 	platform cmd.Platform
 }
@@ -38,6 +52,16 @@ func (b *buildCmd) DefineFlags() {
 	cmd.FlagLayersDir(&b.layersDir)
 	cmd.FlagAppDir(&b.appDir)
 	cmd.FlagPlatformDir(&b.platformDir)
+	cmd.FlagBuildTimeout(&b.buildTimeout)
+	cmd.FlagBuildReportPath(&b.buildReportPath)
+	cmd.FlagBuildEnvReportPath(&b.envReportPath)
+	cmd.FlagBuildLogsDir(&b.buildLogsDir)
+	cmd.FlagBuildLogTail(&b.buildLogTail)
+	cmd.FlagBuildCheckpointPath(&b.checkpointPath)
+	cmd.FlagBuildResume(&b.resume)
+	cmd.FlagOffline(&b.offline)
+	cmd.FlagTrustPolicyPath(&b.trustPolicyPath)
+	cmd.FlagRootDirsPath(&b.rootDirsPath)
 }
 
 func (b *buildCmd) Args(nargs int, args []string) error {
@@ -53,6 +77,25 @@ func (b *buildCmd) Args(nargs int, args []string) error {
 		b.planPath = cmd.DefaultPlanPath(b.platform.API(), b.layersDir)
 	}
 
+	if b.buildReportPath == cmd.PlaceholderBuildReportPath {
+		b.buildReportPath = cmd.DefaultBuildReportPath(b.platform.API(), b.layersDir)
+	}
+
+	if b.checkpointPath == cmd.PlaceholderBuildCheckpointPath {
+		b.checkpointPath = cmd.DefaultBuildCheckpointPath(b.platform.API(), b.layersDir)
+	}
+
+	var err error
+	if b.timeouts, err = buildpack.ParseTimeouts(b.buildTimeout); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "parse build timeout")
+	}
+	if b.trustPolicy, err = readTrustPolicy(b.trustPolicyPath); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "read trust policy")
+	}
+	if b.rootDirs, err = readRootDirs(b.rootDirsPath, b.platformDir); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "read root dirs")
+	}
+
 	return nil
 }
 
@@ -76,10 +119,17 @@ func (b *buildCmd) Exec() error {
 }
 
 func (ba buildArgs) build(group buildpack.Group, plan platform.BuildPlan) error {
-	buildpackStore, err := buildpack.NewBuildpackStore(ba.buildpacksDir)
+	buildpackStore, err := buildpack.NewStore(ba.buildpacksDir)
 	if err != nil {
 		return cmd.FailErrCode(err, ba.platform.CodeFor(cmd.BuildError), "build")
 	}
+	defer buildpack.CloseStore(buildpackStore)
+	if ba.trustPolicy != nil {
+		buildpackStore = &buildpack.TrustedStore{Store: buildpackStore, Policy: ba.trustPolicy}
+	}
+
+	ctx, stop := cmd.InterruptContext()
+	defer stop()
 
 	builder := &lifecycle.Builder{
 		AppDir:         ba.appDir,
@@ -93,14 +143,44 @@ func (ba buildArgs) build(group buildpack.Group, plan platform.BuildPlan) error
 		Err:            cmd.Stderr,
 		Logger:         cmd.DefaultLogger,
 		BuildpackStore: buildpackStore,
+		Timeouts:       ba.timeouts,
+		Context:        ctx,
+		Report:         &platform.BuildStatsReport{},
+		LogsDir:        ba.buildLogsDir,
+		LogTail:        ba.buildLogTail,
+		CheckpointPath: ba.checkpointPath,
+		Resume:         ba.resume,
+		Offline:        ba.offline,
+		RootDirs:       ba.rootDirs,
+	}
+	if ba.envReportPath != "" {
+		builder.EnvReport = &platform.BuildEnvReport{}
 	}
 	md, err := builder.Build()
 
+	if reportErr := lifecycle.WriteTOML(ba.buildReportPath, builder.Report); reportErr != nil {
+		if err == nil {
+			return cmd.FailErr(reportErr, "write build report")
+		}
+		cmd.DefaultLogger.Warnf("Failed to write build report: %s", reportErr)
+	}
+	if builder.EnvReport != nil {
+		if reportErr := lifecycle.WriteTOML(ba.envReportPath, builder.EnvReport); reportErr != nil {
+			if err == nil {
+				return cmd.FailErr(reportErr, "write build env report")
+			}
+			cmd.DefaultLogger.Warnf("Failed to write build env report: %s", reportErr)
+		}
+	}
+
 	if err != nil {
 		if err, ok := err.(*buildpack.Error); ok {
 			if err.Type == buildpack.ErrTypeBuildpack {
 				return cmd.FailErrCode(err.Cause(), ba.platform.CodeFor(cmd.FailedBuildWithErrors), "build")
 			}
+			if err.Type == buildpack.ErrTypeInterrupted {
+				return cmd.FailErrCode(err.Cause(), ba.platform.CodeFor(cmd.BuildInterrupted), "build")
+			}
 		}
 		return cmd.FailErrCode(err, ba.platform.CodeFor(cmd.BuildError), "build")
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/docker/docker/client"
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -11,6 +12,7 @@ import (
 	"github.com/buildpacks/lifecycle/auth"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/env"
 	"github.com/buildpacks/lifecycle/image"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/priv"
@@ -19,12 +21,22 @@ import (
 type createCmd struct {
 	//flags: inputs
 	appDir              string
+	buildEnvReportPath  string
+	buildLogsDir        string
+	buildMixins         string
+	buildReportPath     string
+	buildTimeout        string
 	buildpacksDir       string
 	cacheDir            string
 	cacheImageRef       string
+	checkpointPath      string
+	detectCacheDir      string
+	detectReportPath    string
+	detectTimeout       string
 	launchCacheDir      string
 	launcherPath        string
 	layersDir           string
+	orderGroup          string
 	orderPath           string
 	outputImageRef      string
 	platformDir         string
@@ -32,30 +44,59 @@ type createCmd struct {
 	processType         string
 	projectMetadataPath string
 	reportPath          string
+	rootDirsPath        string
 	runImageRef         string
+	stackID             string
 	stackPath           string
 	targetRegistry      string
+	trustPolicyPath     string
+	buildLogTail        int
+	detectConcurrency   int
+	detectSpeculate     int
 	uid, gid            int
+	detectStream        bool
+	offline             bool
+	resume              bool
 	skipRestore         bool
 	useDaemon           bool
 
 	additionalTags cmd.StringSlice
+	buildTimeouts  buildpack.Timeouts
+	detectTimeouts buildpack.Timeouts
 	docker         client.CommonAPIClient // construct if necessary before dropping privileges
 	keychain       authn.Keychain
+	mixins         []string
 	platform       cmd.Platform
+	rootDirs       env.RootDirs
 	stackMD        platform.StackMetadata
+	trustPolicy    *buildpack.TrustPolicy
 }
 
 func (c *createCmd) DefineFlags() {
 	cmd.FlagAppDir(&c.appDir)
 	cmd.FlagBuildpacksDir(&c.buildpacksDir)
+	cmd.FlagBuildTimeout(&c.buildTimeout)
+	cmd.FlagBuildReportPath(&c.buildReportPath)
+	cmd.FlagBuildEnvReportPath(&c.buildEnvReportPath)
+	cmd.FlagBuildLogsDir(&c.buildLogsDir)
+	cmd.FlagBuildLogTail(&c.buildLogTail)
+	cmd.FlagBuildCheckpointPath(&c.checkpointPath)
+	cmd.FlagBuildResume(&c.resume)
 	cmd.FlagCacheDir(&c.cacheDir)
 	cmd.FlagCacheImage(&c.cacheImageRef)
+	cmd.FlagDetectCacheDir(&c.detectCacheDir)
+	cmd.FlagDetectSpeculate(&c.detectSpeculate)
+	cmd.FlagDetectConcurrency(&c.detectConcurrency)
+	cmd.FlagDetectReportPath(&c.detectReportPath)
+	cmd.FlagDetectStream(&c.detectStream)
+	cmd.FlagOffline(&c.offline)
+	cmd.FlagDetectTimeout(&c.detectTimeout)
 	cmd.FlagGID(&c.gid)
 	cmd.FlagLaunchCacheDir(&c.launchCacheDir)
 	cmd.FlagLauncherPath(&c.launcherPath)
 	cmd.FlagLayersDir(&c.layersDir)
 	cmd.FlagOrderPath(&c.orderPath)
+	cmd.FlagOrderGroup(&c.orderGroup)
 	cmd.FlagPlatformDir(&c.platformDir)
 	cmd.FlagPreviousImage(&c.previousImageRef)
 	cmd.FlagReportPath(&c.reportPath)
@@ -65,6 +106,10 @@ func (c *createCmd) DefineFlags() {
 	cmd.FlagUID(&c.uid)
 	cmd.FlagUseDaemon(&c.useDaemon)
 	cmd.FlagTags(&c.additionalTags)
+	cmd.FlagTrustPolicyPath(&c.trustPolicyPath)
+	cmd.FlagRootDirsPath(&c.rootDirsPath)
+	cmd.FlagStackID(&c.stackID)
+	cmd.FlagBuildMixins(&c.buildMixins)
 	cmd.FlagProjectMetadataPath(&c.projectMetadataPath)
 	cmd.FlagProcessType(&c.processType)
 }
@@ -100,11 +145,39 @@ func (c *createCmd) Args(nargs int, args []string) error {
 		c.reportPath = cmd.DefaultReportPath(c.platform.API(), c.layersDir)
 	}
 
+	if c.buildReportPath == cmd.PlaceholderBuildReportPath {
+		c.buildReportPath = cmd.DefaultBuildReportPath(c.platform.API(), c.layersDir)
+	}
+
+	if c.checkpointPath == cmd.PlaceholderBuildCheckpointPath {
+		c.checkpointPath = cmd.DefaultBuildCheckpointPath(c.platform.API(), c.layersDir)
+	}
+
 	if c.orderPath == cmd.PlaceholderOrderPath {
 		c.orderPath = cmd.DefaultOrderPath(c.platform.API(), c.layersDir)
 	}
 
+	if c.detectReportPath == cmd.PlaceholderDetectReportPath {
+		c.detectReportPath = cmd.DefaultDetectReportPath(c.platform.API(), c.layersDir)
+	}
+
 	var err error
+	if c.detectTimeouts, err = buildpack.ParseTimeouts(c.detectTimeout); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "parse detect timeout")
+	}
+	if c.buildTimeouts, err = buildpack.ParseTimeouts(c.buildTimeout); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "parse build timeout")
+	}
+	if c.trustPolicy, err = readTrustPolicy(c.trustPolicyPath); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "read trust policy")
+	}
+	if c.rootDirs, err = readRootDirs(c.rootDirsPath, c.platformDir); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "read root dirs")
+	}
+	if c.mixins, err = parseMixins(c.buildMixins); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "parse build mixins")
+	}
+
 	c.stackMD, err = readStack(c.stackPath)
 	if err != nil {
 		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "parse stack metadata")
@@ -159,11 +232,40 @@ func (c *createCmd) Exec() error {
 		return err
 	}
 
+	// Layers are not restored when resuming, as they would replace those the completed buildpacks created.
+	skipRestore := c.skipRestore
+	if c.resume {
+		if _, err := os.Stat(c.checkpointPath); err == nil {
+			cmd.DefaultLogger.Infof("Resuming the build from checkpoint '%s', layers will not be restored", c.checkpointPath)
+			skipRestore = true
+		}
+	}
+
 	var (
 		analyzedMD platform.AnalyzedMetadata
 		group      buildpack.Group
 		plan       platform.BuildPlan
 	)
+	da := detectArgs{
+		buildpacksDir:    c.buildpacksDir,
+		appDir:           c.appDir,
+		layersDir:        c.layersDir,
+		platform:         c.platform,
+		platformDir:      c.platformDir,
+		orderPath:        c.orderPath,
+		orderGroup:       c.orderGroup,
+		detectReportPath: c.detectReportPath,
+		timeouts:         c.detectTimeouts,
+		detectCacheDir:   c.detectCacheDir,
+		speculate:        c.detectSpeculate,
+		concurrency:      c.detectConcurrency,
+		stream:           c.detectStream,
+		offline:          c.offline,
+		trustPolicy:      c.trustPolicy,
+		rootDirs:         c.rootDirs,
+		stackID:          c.stackID,
+		mixins:           c.mixins,
+	}
 	if api.MustParse(c.platform.API()).Compare(api.MustParse("0.7")) >= 0 {
 		cmd.DefaultLogger.Phase("ANALYZING")
 		analyzedMD, err = analyzeArgs{
@@ -183,27 +285,13 @@ func (c *createCmd) Exec() error {
 		}
 
 		cmd.DefaultLogger.Phase("DETECTING")
-		group, plan, err = detectArgs{
-			buildpacksDir: c.buildpacksDir,
-			appDir:        c.appDir,
-			layersDir:     c.layersDir,
-			platform:      c.platform,
-			platformDir:   c.platformDir,
-			orderPath:     c.orderPath,
-		}.detect()
+		group, plan, err = da.detect()
 		if err != nil {
 			return err
 		}
 	} else {
 		cmd.DefaultLogger.Phase("DETECTING")
-		group, plan, err = detectArgs{
-			buildpacksDir: c.buildpacksDir,
-			appDir:        c.appDir,
-			layersDir:     c.layersDir,
-			platform:      c.platform,
-			platformDir:   c.platformDir,
-			orderPath:     c.orderPath,
-		}.detect()
+		group, plan, err = da.detect()
 		if err != nil {
 			return err
 		}
@@ -217,7 +305,7 @@ func (c *createCmd) Exec() error {
 			platform:         c.platform,
 			useDaemon:        c.useDaemon,
 			platform06: analyzeArgsPlatform06{
-				skipLayers: c.skipRestore,
+				skipLayers: skipRestore,
 				group:      group,
 				cache:      cacheStore,
 			},
@@ -227,13 +315,13 @@ func (c *createCmd) Exec() error {
 		}
 	}
 
-	if !c.skipRestore {
+	if !skipRestore {
 		cmd.DefaultLogger.Phase("RESTORING")
 		err := restoreArgs{
 			keychain:   c.keychain,
 			layersDir:  c.layersDir,
 			platform:   c.platform,
-			skipLayers: c.skipRestore,
+			skipLayers: skipRestore,
 		}.restore(analyzedMD.Metadata, group, cacheStore)
 		if err != nil {
 			return err
@@ -242,11 +330,21 @@ func (c *createCmd) Exec() error {
 
 	cmd.DefaultLogger.Phase("BUILDING")
 	err = buildArgs{
-		buildpacksDir: c.buildpacksDir,
-		layersDir:     c.layersDir,
-		appDir:        c.appDir,
-		platform:      c.platform,
-		platformDir:   c.platformDir,
+		buildpacksDir:   c.buildpacksDir,
+		layersDir:       c.layersDir,
+		appDir:          c.appDir,
+		buildLogsDir:    c.buildLogsDir,
+		buildLogTail:    c.buildLogTail,
+		buildReportPath: c.buildReportPath,
+		checkpointPath:  c.checkpointPath,
+		envReportPath:   c.buildEnvReportPath,
+		platform:        c.platform,
+		platformDir:     c.platformDir,
+		offline:         c.offline,
+		resume:          c.resume,
+		timeouts:        c.buildTimeouts,
+		trustPolicy:     c.trustPolicy,
+		rootDirs:        c.rootDirs,
 	}.build(group, plan)
 	if err != nil {
 		return err
@@ -255,6 +353,7 @@ func (c *createCmd) Exec() error {
 	cmd.DefaultLogger.Phase("EXPORTING")
 	return exportArgs{
 		appDir:              c.appDir,
+		buildReportPath:     c.buildReportPath,
 		docker:              c.docker,
 		gid:                 c.gid,
 		imageNames:          append([]string{c.outputImageRef}, c.additionalTags...),
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
 
 	"github.com/buildpacks/lifecycle"
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/cmd"
+	"github.com/buildpacks/lifecycle/env"
+	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/platform"
 	"github.com/buildpacks/lifecycle/priv"
 )
@@ -18,6 +27,12 @@ type detectCmd struct {
 	// flags: paths to write outputs
 	groupPath string
 	planPath  string
+
+	buildMixins     string
+	detectTimeout   string
+	rootDirsPath    string
+	simulationPath  string
+	trustPolicyPath string
 }
 
 type detectArgs struct {
@@ -27,6 +42,39 @@ type detectArgs struct {
 	layersDir     string
 	platformDir   string
 	orderPath     string
+	orderGroup    string // optional; restricts detection to one group of the order
+	timeouts      buildpack.Timeouts
+
+	// optional recorded detect results, used instead of the buildpacks in buildpacksDir
+	simulation *lifecycle.SimulatedStore
+
+	// optional stack that every buildpack must support
+	stackID string
+	mixins  []string // of the build image
+
+	// optional policy that every buildpack must satisfy before it is executed
+	trustPolicy *buildpack.TrustPolicy
+
+	// optional extension of the layer directories added to env vars
+	rootDirs env.RootDirs
+
+	// optional detect result cache, reused across builds
+	detectCacheDir string
+
+	// number of detect runs that may be started early for later order groups
+	speculate int
+
+	// maximum number of detect runs in progress at once
+	concurrency int
+
+	// whether to print detect output as it is produced
+	stream bool
+
+	// whether to run bin/detect without network access
+	offline bool
+
+	// optional output, written whether or not detection passes
+	detectReportPath string
 
 	platform cmd.Platform
 }
@@ -37,8 +85,21 @@ func (d *detectCmd) DefineFlags() {
 	cmd.FlagLayersDir(&d.layersDir)
 	cmd.FlagPlatformDir(&d.platformDir)
 	cmd.FlagOrderPath(&d.orderPath)
+	cmd.FlagOrderGroup(&d.orderGroup)
 	cmd.FlagGroupPath(&d.groupPath)
 	cmd.FlagPlanPath(&d.planPath)
+	cmd.FlagDetectReportPath(&d.detectReportPath)
+	cmd.FlagDetectTimeout(&d.detectTimeout)
+	cmd.FlagDetectCacheDir(&d.detectCacheDir)
+	cmd.FlagDetectSpeculate(&d.speculate)
+	cmd.FlagDetectConcurrency(&d.concurrency)
+	cmd.FlagDetectSimulation(&d.simulationPath)
+	cmd.FlagDetectStream(&d.stream)
+	cmd.FlagOffline(&d.offline)
+	cmd.FlagTrustPolicyPath(&d.trustPolicyPath)
+	cmd.FlagRootDirsPath(&d.rootDirsPath)
+	cmd.FlagStackID(&d.stackID)
+	cmd.FlagBuildMixins(&d.buildMixins)
 }
 
 func (d *detectCmd) Args(nargs int, args []string) error {
@@ -58,6 +119,29 @@ func (d *detectCmd) Args(nargs int, args []string) error {
 		d.orderPath = cmd.DefaultOrderPath(d.platform.API(), d.layersDir)
 	}
 
+	if d.detectReportPath == cmd.PlaceholderDetectReportPath {
+		d.detectReportPath = cmd.DefaultDetectReportPath(d.platform.API(), d.layersDir)
+	}
+
+	var err error
+	if d.timeouts, err = buildpack.ParseTimeouts(d.detectTimeout); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "parse detect timeout")
+	}
+	if d.trustPolicy, err = readTrustPolicy(d.trustPolicyPath); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "read trust policy")
+	}
+	if d.rootDirs, err = readRootDirs(d.rootDirsPath, d.platformDir); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "read root dirs")
+	}
+	if d.mixins, err = parseMixins(d.buildMixins); err != nil {
+		return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "parse build mixins")
+	}
+	if d.simulationPath != "" {
+		if d.simulation, err = lifecycle.ReadSimulatedStore(d.simulationPath); err != nil {
+			return cmd.FailErrCode(err, cmd.CodeInvalidArgs, "read simulated detect results")
+		}
+	}
+
 	return nil
 }
 
@@ -74,6 +158,9 @@ func (d *detectCmd) Exec() error {
 	if err != nil {
 		return err
 	}
+	if d.simulation != nil {
+		return printSimulation(group, plan)
+	}
 	return d.writeData(group, plan)
 }
 
@@ -82,15 +169,31 @@ func (da detectArgs) detect() (buildpack.Group, platform.BuildPlan, error) {
 	if err != nil {
 		return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErr(err, "read buildpack order file")
 	}
-	if err := da.verifyBuildpackApis(order); err != nil {
-		return buildpack.Group{}, platform.BuildPlan{}, err
+	if da.orderGroup != "" {
+		if order, err = lifecycle.SelectGroup(order, da.orderGroup); err != nil {
+			return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErrCode(err, cmd.CodeInvalidArgs, "select order group")
+		}
+		cmd.DefaultLogger.Infof("Only detecting group %s", da.orderGroup)
 	}
+	if da.simulation == nil {
+		if err := da.verifyBuildpackApis(order); err != nil {
+			return buildpack.Group{}, platform.BuildPlan{}, err
+		}
+	}
+
+	ctx, stop := cmd.InterruptContext()
+	defer stop()
 
 	detector, err := lifecycle.NewDetector(
 		buildpack.DetectConfig{
 			AppDir:      da.appDir,
 			PlatformDir: da.platformDir,
 			Logger:      cmd.DefaultLogger,
+			Timeouts:    da.timeouts,
+			Context:     ctx,
+			StackID:     da.stackID,
+			BuildMixins: da.mixins,
+			Offline:     da.offline,
 		},
 		da.buildpacksDir,
 		da.platform,
@@ -98,18 +201,51 @@ func (da detectArgs) detect() (buildpack.Group, platform.BuildPlan, error) {
 	if err != nil {
 		return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErr(err, "initialize detector")
 	}
+	defer buildpack.CloseStore(detector.Store)
+	if da.stream {
+		detector.Stream = &buildpack.LineStream{W: cmd.Stdout}
+	}
+	detector.Speculate = da.speculate
+	detector.RootDirs = da.rootDirs
+	detector.Concurrency = da.concurrency
+	if da.simulation != nil {
+		detector.Store = da.simulation
+	} else if da.trustPolicy != nil {
+		detector.Store = &buildpack.TrustedStore{Store: detector.Store, Policy: da.trustPolicy}
+	}
+	if da.detectCacheDir != "" && da.simulation == nil {
+		if detector.Cache, err = lifecycle.NewDetectCache(da.detectCacheDir, da.appDir, da.platformDir, cmd.DefaultLogger); err != nil {
+			return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErr(err, "initialize detect cache")
+		}
+	}
 	group, plan, err := detector.Detect(order)
+	if da.detectReportPath != "" && da.simulation == nil {
+		if err := writeDetectReport(da.detectReportPath, detector.Report); err != nil {
+			return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErr(err, "write detect report")
+		}
+	}
 	if err != nil {
 		switch err := err.(type) {
 		case *buildpack.Error:
 			switch err.Type {
 			case buildpack.ErrTypeFailedDetection:
+				if da.orderGroup != "" {
+					explainDetectFailure(detector.Report)
+				}
 				cmd.DefaultLogger.Error("No buildpack groups passed detection.")
 				cmd.DefaultLogger.Error("Please check that you are running against the correct path.")
 				return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErrCode(err, da.platform.CodeFor(cmd.FailedDetect), "detect")
 			case buildpack.ErrTypeBuildpack:
+				if da.orderGroup != "" {
+					explainDetectFailure(detector.Report)
+				}
 				cmd.DefaultLogger.Error("No buildpack groups passed detection.")
 				return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErrCode(err, da.platform.CodeFor(cmd.FailedDetectWithErrors), "detect")
+			case buildpack.ErrTypeInterrupted:
+				return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErrCode(err, da.platform.CodeFor(cmd.DetectInterrupted), "detect")
+			case buildpack.ErrTypeUntrusted:
+				cmd.DefaultLogger.Error("Refusing to run an untrusted buildpack.")
+				return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErrCode(err.Cause(), da.platform.CodeFor(cmd.DetectError), "detect")
 			default:
 				return buildpack.Group{}, platform.BuildPlan{}, cmd.FailErrCode(err, da.platform.CodeFor(cmd.DetectError), "detect")
 			}
@@ -122,10 +258,11 @@ func (da detectArgs) detect() (buildpack.Group, platform.BuildPlan, error) {
 }
 
 func (da detectArgs) verifyBuildpackApis(order buildpack.Order) error {
-	store, err := buildpack.NewBuildpackStore(da.buildpacksDir)
+	store, err := buildpack.NewStore(da.buildpacksDir)
 	if err != nil {
 		return err
 	}
+	defer buildpack.CloseStore(store)
 	for _, group := range order {
 		for _, groupBp := range group.Group {
 			buildpack, err := store.Lookup(groupBp.ID, groupBp.Version)
@@ -140,6 +277,121 @@ func (da detectArgs) verifyBuildpackApis(order buildpack.Order) error {
 	return nil
 }
 
+// explainDetectFailure logs why each group in the report failed: the buildpacks that failed detection, or the
+// requirements that kept a build plan from resolving.
+func explainDetectFailure(report *platform.DetectReport) {
+	for _, group := range report.Groups {
+		var bps []string
+		for _, bp := range group.Buildpacks {
+			bps = append(bps, bp.ID+"@"+bp.Version)
+		}
+		cmd.DefaultLogger.Errorf("Group [%s] did not pass detection:", strings.Join(bps, ", "))
+		for _, bp := range group.Buildpacks {
+			if bp.Result != "fail" && bp.Result != "error" {
+				continue
+			}
+			detail := fmt.Sprintf("exit code %d", bp.ExitCode)
+			if bp.Reason != "" {
+				detail = bp.Reason
+			}
+			if bp.Error != "" {
+				detail += ": " + bp.Error
+			}
+			cmd.DefaultLogger.Errorf("  %s@%s: %s (%s)", bp.ID, bp.Version, bp.Result, detail)
+		}
+		seen := map[string]bool{}
+		for _, trial := range group.Trials {
+			for _, rejection := range trial.Rejections {
+				if rejection.Action != "fail" {
+					continue
+				}
+				if line := describeRejection(rejection); !seen[line] {
+					seen[line] = true
+					cmd.DefaultLogger.Errorf("  %s", line)
+				}
+			}
+		}
+	}
+}
+
+func describeRejection(r platform.DetectRejectionReport) string {
+	bp := r.ID + "@" + r.Version
+	switch r.Reason {
+	case "requires":
+		return fmt.Sprintf("%s requires %s, but no buildpack before it provides %s", bp, r.Name, r.Name)
+	case "provides-unused":
+		return fmt.Sprintf("%s provides %s, but no buildpack requires %s", bp, r.Name, r.Name)
+	case "version-conflict":
+		return fmt.Sprintf("%s requires %s %s, but %s provides %s %s", bp, r.Name, r.Constraint, r.Provider, r.Name, r.Provided)
+	case "no-viable-buildpacks":
+		return "no buildpacks in the group passed detection"
+	}
+	return fmt.Sprintf("%s: %s %s", bp, r.Reason, r.Name)
+}
+
+// readTrustPolicy returns nil if no trust policy is configured.
+func readTrustPolicy(path string) (*buildpack.TrustPolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return buildpack.ReadTrustPolicy(path)
+}
+
+// readRootDirs reads the root dirs from path or, if path is empty, from the first of <platform>/root-dirs.toml and
+// <lifecycle>/root-dirs.toml that exists. It returns no root dirs if none are configured.
+func readRootDirs(path, platformDir string) (env.RootDirs, error) {
+	if path == "" {
+		for _, candidate := range []string{
+			filepath.Join(platformDir, cmd.DefaultRootDirsFile),
+			filepath.Join(launch.LifecycleDir, cmd.DefaultRootDirsFile),
+		} {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return env.RootDirs{}, nil
+		}
+	}
+	return env.ReadRootDirs(path)
+}
+
+// parseMixins parses a comma-separated list of mixins or a JSON array, as found in the io.buildpacks.stack.mixins
+// label. It returns nil if no mixins are configured.
+func parseMixins(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	mixins := []string{}
+	if strings.HasPrefix(s, "[") {
+		err := json.Unmarshal([]byte(s), &mixins)
+		return mixins, err
+	}
+	for _, m := range strings.Split(s, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			mixins = append(mixins, m)
+		}
+	}
+	return mixins, nil
+}
+
+// printSimulation prints the group and plan that simulated detection selected, in the format of group.toml and
+// plan.toml.
+func printSimulation(group buildpack.Group, plan platform.BuildPlan) error {
+	if err := toml.NewEncoder(cmd.Stdout).Encode(group); err != nil {
+		return cmd.FailErr(err, "print buildpack group")
+	}
+	if _, err := fmt.Fprintln(cmd.Stdout); err != nil {
+		return cmd.FailErr(err, "print detect plan")
+	}
+	if err := toml.NewEncoder(cmd.Stdout).Encode(plan); err != nil {
+		return cmd.FailErr(err, "print detect plan")
+	}
+	return nil
+}
+
 func (d *detectCmd) writeData(group buildpack.Group, plan platform.BuildPlan) error {
 	if err := lifecycle.WriteTOML(d.groupPath, group); err != nil {
 		return cmd.FailErr(err, "write buildpack group")
@@ -150,3 +402,17 @@ func (d *detectCmd) writeData(group buildpack.Group, plan platform.BuildPlan) er
 	}
 	return nil
 }
+
+func writeDetectReport(path string, report *platform.DetectReport) error {
+	if filepath.Ext(path) != ".json" {
+		return lifecycle.WriteTOML(path, report)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0666)
+}
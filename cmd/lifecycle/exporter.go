@@ -42,6 +42,7 @@ type exportCmd struct {
 type exportArgs struct {
 	// inputs needed when run by creator
 	appDir              string
+	buildReportPath     string
 	launchCacheDir      string
 	launcherPath        string
 	layersDir           string
@@ -67,6 +68,7 @@ type exportArgs struct {
 func (e *exportCmd) DefineFlags() {
 	cmd.FlagAnalyzedPath(&e.analyzedPath)
 	cmd.FlagAppDir(&e.appDir)
+	cmd.FlagBuildReportPath(&e.buildReportPath)
 	cmd.FlagCacheDir(&e.cacheDir)
 	cmd.FlagCacheImage(&e.cacheImageTag)
 	cmd.FlagGID(&e.gid)
@@ -124,6 +126,10 @@ func (e *exportCmd) Args(nargs int, args []string) error {
 		e.reportPath = cmd.DefaultReportPath(e.platform.API(), e.layersDir)
 	}
 
+	if e.buildReportPath == cmd.PlaceholderBuildReportPath {
+		e.buildReportPath = cmd.DefaultBuildReportPath(e.platform.API(), e.layersDir)
+	}
+
 	if e.deprecatedRunImageRef != "" {
 		e.runImageRef = e.deprecatedRunImageRef
 	}
@@ -309,6 +315,12 @@ func (ea exportArgs) export(group buildpack.Group, cacheStore lifecycle.Cache, a
 	if err != nil {
 		return cmd.FailErrCode(err, ea.platform.CodeFor(cmd.ExportError), "export")
 	}
+	var buildStats platform.BuildStatsReport
+	if _, err := toml.DecodeFile(ea.buildReportPath, &buildStats); err != nil && !os.IsNotExist(err) {
+		cmd.DefaultLogger.Warnf("Failed to read build report: %s", err)
+	}
+	report.Build.Buildpacks = buildStats.Buildpacks
+	report.Build.Offline = buildStats.Offline
 	if err := lifecycle.WriteTOML(ea.reportPath, &report); err != nil {
 		return cmd.FailErrCode(err, ea.platform.CodeFor(cmd.ExportError), "write export report")
 	}
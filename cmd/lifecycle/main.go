@@ -15,6 +15,10 @@ import (
 )
 
 func main() {
+	if filepath.Base(os.Args[0]) == buildpack.IsolatedExecName {
+		buildpack.IsolatedExec() // re-executed to run a buildpack with the network isolated
+	}
+
 	platformAPI := cmd.EnvOrDefault(cmd.EnvPlatformAPI, cmd.DefaultPlatformAPI)
 	if err := cmd.VerifyPlatformAPI(platformAPI); err != nil {
 		cmd.Exit(err)
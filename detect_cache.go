@@ -0,0 +1,172 @@
+package lifecycle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+)
+
+// volatileEnvVars are excluded from detect cache keys because they change between otherwise identical builds.
+var volatileEnvVars = []string{"HOSTNAME"}
+
+// DetectCache persists the results of buildpack detection across builds.
+//
+// Results are keyed on the buildpack ID, version and a digest of the buildpack directory, the buildpack's environment
+// including the platform env files, and a digest of the paths, modes and contents of the files in the app directory.
+// Only results that passed or failed detection are cached; errored runs are always retried.
+type DetectCache struct {
+	Dir    string
+	Logger Logger
+
+	base      string // digest of inputs shared by all buildpacks
+	dirHashes sync.Map
+}
+
+// NewDetectCache returns a detect cache stored in dir. The app directory is fingerprinted immediately, so the cache
+// must be created before any buildpack runs.
+func NewDetectCache(dir, appDir, platformDir string, logger Logger) (*DetectCache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if err := fingerprintDir(h, appDir); err != nil {
+		return nil, errors.Wrap(err, "fingerprint app dir")
+	}
+	if err := fingerprintDir(h, filepath.Join(platformDir, "env")); err != nil {
+		return nil, errors.Wrap(err, "fingerprint platform env")
+	}
+	return &DetectCache{
+		Dir:    dir,
+		Logger: logger,
+		base:   hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// Key returns the cache key for running detect for the given buildpack with the given environment.
+func (c *DetectCache) Key(bp buildpack.GroupBuildpack, bpDir string, environ []string) (string, error) {
+	dirHash, err := c.dirHash(bpDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "fingerprint buildpack dir for '%s'", bp)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00", c.base, bp.ID, bp.Version, dirHash)
+	var vars []string
+	for _, kv := range environ {
+		if !isVolatileEnv(kv) {
+			vars = append(vars, kv)
+		}
+	}
+	sort.Strings(vars)
+	for _, kv := range vars {
+		fmt.Fprintf(h, "%s\x00", kv)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type detectCacheEntry struct {
+	Code   int                 `toml:"code"`
+	Output string              `toml:"output"`
+	Plan   buildpack.BuildPlan `toml:"plan"`
+}
+
+// Load returns the cached detect result for the key, if present.
+func (c *DetectCache) Load(key string) (buildpack.DetectRun, bool) {
+	var entry detectCacheEntry
+	if _, err := toml.DecodeFile(c.path(key), &entry); err != nil {
+		if !os.IsNotExist(err) {
+			c.Logger.Debugf("Ignoring unreadable detect cache entry '%s': %s", key, err)
+		}
+		return buildpack.DetectRun{}, false
+	}
+	return buildpack.DetectRun{
+		BuildPlan: entry.Plan,
+		Code:      entry.Code,
+		Output:    []byte(entry.Output),
+	}, true
+}
+
+// Store saves the detect result for the key. Runs that errored are not stored.
+func (c *DetectCache) Store(key string, run buildpack.DetectRun) error {
+	if run.Err != nil || (run.Code != CodeDetectPass && run.Code != CodeDetectFail) {
+		return nil
+	}
+	return WriteTOML(c.path(key), detectCacheEntry{
+		Code:   run.Code,
+		Output: string(run.Output),
+		Plan:   run.BuildPlan,
+	})
+}
+
+func (c *DetectCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".toml")
+}
+
+func (c *DetectCache) dirHash(dir string) (string, error) {
+	if v, ok := c.dirHashes.Load(dir); ok {
+		return v.(string), nil
+	}
+	h := sha256.New()
+	if err := fingerprintDir(h, dir); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	c.dirHashes.Store(dir, sum)
+	return sum, nil
+}
+
+// fingerprintDir writes the relative path, mode, size and contents of each file under dir to h.
+// A missing dir has an empty fingerprint.
+func fingerprintDir(h hash.Hash, dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00%d\x00", filepath.ToSlash(rel), fi.Mode(), fi.Size())
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00", target)
+		case fi.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func isVolatileEnv(kv string) bool {
+	for _, k := range volatileEnvVars {
+		if strings.HasPrefix(kv, k+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package lifecycle_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/golang/mock/gomock"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/platform"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+	"github.com/buildpacks/lifecycle/testmock"
+)
+
+func TestDetectCache(t *testing.T) {
+	spec.Run(t, "DetectCache", testDetectCache, spec.Report(report.Terminal{}))
+}
+
+func testDetectCache(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir      string
+		cacheDir    string
+		appDir      string
+		platformDir string
+		bpDir       string
+		logger      *log.Logger
+		groupBp     = buildpack.GroupBuildpack{ID: "A", Version: "v1"}
+		environ     = []string{"HOSTNAME=host-1", "PATH=/some/bin"}
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "lifecycle.detect-cache")
+		h.AssertNil(t, err)
+		cacheDir = filepath.Join(tmpDir, "cache")
+		appDir = filepath.Join(tmpDir, "app")
+		platformDir = filepath.Join(tmpDir, "platform")
+		bpDir = filepath.Join(tmpDir, "buildpacks", "A", "v1")
+		h.Mkdir(t, appDir, filepath.Join(platformDir, "env"), filepath.Join(bpDir, "bin"))
+		h.Mkfile(t, "{}", filepath.Join(appDir, "package.json"))
+		h.Mkfile(t, "#!/bin/sh", filepath.Join(bpDir, "bin", "detect"))
+		logger = &log.Logger{Handler: memory.New()}
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	newCache := func() *lifecycle.DetectCache {
+		t.Helper()
+		cache, err := lifecycle.NewDetectCache(cacheDir, appDir, platformDir, logger)
+		h.AssertNil(t, err)
+		return cache
+	}
+
+	key := func(cache *lifecycle.DetectCache, environ []string) string {
+		t.Helper()
+		k, err := cache.Key(groupBp, bpDir, environ)
+		h.AssertNil(t, err)
+		return k
+	}
+
+	when("#Key", func() {
+		it("is stable for unchanged inputs", func() {
+			h.AssertEq(t, key(newCache(), environ), key(newCache(), environ))
+		})
+
+		it("ignores volatile env vars", func() {
+			h.AssertEq(t, key(newCache(), environ), key(newCache(), []string{"HOSTNAME=host-2", "PATH=/some/bin"}))
+		})
+
+		it("changes when the buildpack env changes", func() {
+			if key(newCache(), environ) == key(newCache(), []string{"PATH=/other/bin"}) {
+				t.Fatal("Expected keys to differ")
+			}
+		})
+
+		it("changes when the app dir changes", func() {
+			before := key(newCache(), environ)
+			h.Mkfile(t, "module foo", filepath.Join(appDir, "go.mod"))
+			if before == key(newCache(), environ) {
+				t.Fatal("Expected keys to differ")
+			}
+		})
+
+		it("changes when app file contents change but modification times do not", func() {
+			path := filepath.Join(appDir, "package.json")
+			fi, err := os.Stat(path)
+			h.AssertNil(t, err)
+			before := key(newCache(), environ)
+			h.Mkfile(t, "[]", path)
+			h.AssertNil(t, os.Chtimes(path, fi.ModTime(), fi.ModTime()))
+			if before == key(newCache(), environ) {
+				t.Fatal("Expected keys to differ")
+			}
+		})
+
+		it("changes when the platform env changes", func() {
+			before := key(newCache(), environ)
+			h.Mkfile(t, "some-token", filepath.Join(platformDir, "env", "NPM_TOKEN"))
+			if before == key(newCache(), environ) {
+				t.Fatal("Expected keys to differ")
+			}
+		})
+
+		it("changes when the buildpack contents change", func() {
+			before := key(newCache(), environ)
+			h.Mkfile(t, "#!/bin/sh\nexit 100", filepath.Join(bpDir, "bin", "detect"))
+			if before == key(newCache(), environ) {
+				t.Fatal("Expected keys to differ")
+			}
+		})
+	})
+
+	when("#Store", func() {
+		it("round trips detect results", func() {
+			cache := newCache()
+			run := buildpack.DetectRun{
+				BuildPlan: buildpack.BuildPlan{
+					PlanSections: buildpack.PlanSections{
+						Requires: []buildpack.Require{{Name: "dep1", Metadata: map[string]interface{}{"version": "1.2.3"}}},
+						Provides: []buildpack.Provide{{Name: "dep1"}},
+					},
+					Or: []buildpack.PlanSections{
+						{Provides: []buildpack.Provide{{Name: "dep2"}}},
+					},
+				},
+				Output: []byte("some output"),
+			}
+			h.AssertNil(t, cache.Store("some-key", run))
+
+			loaded, ok := cache.Load("some-key")
+			if !ok {
+				t.Fatal("Expected cached result")
+			}
+			h.AssertEq(t, loaded, run)
+		})
+
+		it("does not store errored runs", func() {
+			cache := newCache()
+			h.AssertNil(t, cache.Store("some-key", buildpack.DetectRun{Code: 1}))
+
+			if _, ok := cache.Load("some-key"); ok {
+				t.Fatal("Expected no cached result")
+			}
+		})
+	})
+
+	when("used by the detector", func() {
+		var (
+			mockCtrl *gomock.Controller
+			store    *testmock.MockBuildpackStore
+			resolver *testmock.MockResolver
+			plat     *testmock.MockPlatform
+		)
+
+		it.Before(func() {
+			mockCtrl = gomock.NewController(t)
+			store = testmock.NewMockBuildpackStore(mockCtrl)
+			resolver = testmock.NewMockResolver(mockCtrl)
+			plat = testmock.NewMockPlatform(mockCtrl)
+			plat.EXPECT().SupportsAssetPackages().Return(false).AnyTimes()
+		})
+
+		it.After(func() {
+			mockCtrl.Finish()
+		})
+
+		it("reuses detect results from previous builds", func() {
+			bpA1 := testmock.NewMockBuildpack(mockCtrl)
+			bpA1.EXPECT().SupportsAssetPackages().Return(false).AnyTimes()
+			bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3", Dir: bpDir}).AnyTimes()
+			store.EXPECT().Lookup("A", "v1").Return(bpA1, nil).Times(2)
+			bpA1.EXPECT().Detect(gomock.Any(), gomock.Any()).Return(buildpack.DetectRun{Output: []byte("ran")}).Times(1)
+
+			group := []buildpack.GroupBuildpack{{ID: "A", Version: "v1", API: "0.3"}}
+			resolver.EXPECT().Resolve(group, gomock.Any()).Return(group, []platform.BuildPlanEntry{}, nil).Times(2)
+
+			for i := 0; i < 2; i++ {
+				runs := &sync.Map{}
+				detector := &lifecycle.Detector{
+					DetectConfig: buildpack.DetectConfig{AppDir: appDir, PlatformDir: platformDir, Logger: logger},
+					Cache:        newCache(),
+					Platform:     plat,
+					Resolver:     resolver,
+					Runs:         runs,
+					Store:        store,
+				}
+				_, _, err := detector.Detect(buildpack.Order{{Group: []buildpack.GroupBuildpack{groupBp}}})
+				h.AssertNil(t, err)
+
+				run, ok := runs.Load("A@v1")
+				if !ok {
+					t.Fatal("Expected detect run")
+				}
+				h.AssertEq(t, string(run.(buildpack.DetectRun).Output), "ran")
+			}
+		})
+	})
+}
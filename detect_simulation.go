@@ -0,0 +1,87 @@
+package lifecycle
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/buildpack"
+)
+
+// SimulatedStore is a buildpack store whose buildpacks return recorded detect results instead of running bin/detect,
+// so that an order can be resolved by the Detector without the buildpacks it references.
+type SimulatedStore struct {
+	Buildpacks []SimulatedBuildpack `toml:"buildpacks"`
+}
+
+// SimulatedBuildpack is a buildpack with a recorded detect result: an exit code, output and build plan.
+// A meta-buildpack lists its order instead.
+type SimulatedBuildpack struct {
+	API      string          `toml:"api"`
+	ID       string          `toml:"id"`
+	Version  string          `toml:"version"`
+	ExitCode int             `toml:"exit-code"`
+	Output   string          `toml:"output"`
+	Order    buildpack.Order `toml:"order"`
+	buildpack.BuildPlan
+}
+
+func ReadSimulatedStore(path string) (*SimulatedStore, error) {
+	store := &SimulatedStore{}
+	if _, err := toml.DecodeFile(path, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Lookup returns the simulated buildpack with the given ID and version, resolving bpVersion against the recorded
+// versions in the same way as a buildpack store resolves it against the installed versions.
+func (s *SimulatedStore) Lookup(bpID, bpVersion string) (buildpack.Buildpack, error) {
+	var recorded []string
+	for _, bp := range s.Buildpacks {
+		if bp.ID == bpID {
+			recorded = append(recorded, bp.Version)
+		}
+	}
+	if len(recorded) == 0 {
+		return nil, errors.Errorf("no simulated detect result for buildpack '%s@%s'", bpID, bpVersion)
+	}
+	version, err := buildpack.ResolveVersion(bpID, bpVersion, recorded)
+	if err != nil {
+		return nil, errors.Wrap(err, "find simulated detect result")
+	}
+	for i := range s.Buildpacks {
+		if bp := &s.Buildpacks[i]; bp.ID == bpID && bp.Version == version {
+			return bp, nil
+		}
+	}
+	return nil, errors.Errorf("no simulated detect result for buildpack '%s@%s'", bpID, version)
+}
+
+func (b *SimulatedBuildpack) Build(_ buildpack.Plan, _ buildpack.BuildConfig, _ buildpack.BuildEnv) (buildpack.BuildResult, error) {
+	return buildpack.BuildResult{}, errors.Errorf("simulated buildpack '%s@%s' cannot build", b.ID, b.Version)
+}
+
+func (b *SimulatedBuildpack) ConfigFile() *buildpack.Descriptor {
+	bpAPI := b.API
+	if bpAPI == "" {
+		bpAPI = api.Buildpack.Latest().String()
+	}
+	return &buildpack.Descriptor{
+		API:       bpAPI,
+		Buildpack: buildpack.Info{ID: b.ID, Name: b.ID, Version: b.Version},
+		Order:     b.Order,
+	}
+}
+
+func (b *SimulatedBuildpack) Detect(_ *buildpack.DetectConfig, _ buildpack.BuildEnv) buildpack.DetectRun {
+	return buildpack.DetectRun{
+		BuildPlan: b.BuildPlan,
+		Code:      b.ExitCode,
+		Output:    []byte(b.Output),
+	}
+}
+
+func (b *SimulatedBuildpack) SupportsAssetPackages() bool {
+	return false
+}
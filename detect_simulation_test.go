@@ -0,0 +1,152 @@
+package lifecycle_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/golang/mock/gomock"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle"
+	"github.com/buildpacks/lifecycle/api"
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/platform"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+	"github.com/buildpacks/lifecycle/testmock"
+)
+
+func TestDetectSimulation(t *testing.T) {
+	spec.Run(t, "DetectSimulation", testDetectSimulation, spec.Report(report.Terminal{}))
+}
+
+func testDetectSimulation(t *testing.T, when spec.G, it spec.S) {
+	var (
+		mockCtrl *gomock.Controller
+		tmpDir   string
+		detector *lifecycle.Detector
+	)
+
+	it.Before(func() {
+		mockCtrl = gomock.NewController(t)
+		var err error
+		tmpDir, err = ioutil.TempDir("", "lifecycle.simulation")
+		h.AssertNil(t, err)
+
+		platformInt := testmock.NewMockPlatform(mockCtrl)
+		platformInt.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+		logger := &log.Logger{Handler: memory.New()}
+		detector = &lifecycle.Detector{
+			DetectConfig: buildpack.DetectConfig{Logger: logger},
+			Platform:     platformInt,
+			Resolver:     &lifecycle.DefaultResolver{Logger: logger},
+			Runs:         &sync.Map{},
+		}
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+		mockCtrl.Finish()
+	})
+
+	readStore := func(contents string) *lifecycle.SimulatedStore {
+		t.Helper()
+		path := filepath.Join(tmpDir, "simulation.toml")
+		h.Mkfile(t, contents, path)
+		store, err := lifecycle.ReadSimulatedStore(path)
+		h.AssertNil(t, err)
+		return store
+	}
+
+	it("should resolve the order against the recorded results", func() {
+		detector.Store = readStore(`
+[[buildpacks]]
+id = "A"
+version = "v1"
+exit-code = 100
+
+[[buildpacks]]
+id = "meta"
+version = "v1"
+[[buildpacks.order]]
+group = [{id = "A", version = "v1"}, {id = "B", version = "v1"}]
+[[buildpacks.order]]
+group = [{id = "C", version = "v1"}, {id = "B", version = "v1"}]
+
+[[buildpacks]]
+id = "B"
+version = "v1"
+[[buildpacks.requires]]
+name = "node"
+
+[[buildpacks]]
+api = "0.5"
+id = "C"
+version = "v1"
+[[buildpacks.provides]]
+name = "node"
+`)
+
+		group, plan, err := detector.Detect(buildpack.Order{
+			{Group: []buildpack.GroupBuildpack{{ID: "meta", Version: "v1"}}},
+		})
+		h.AssertNil(t, err)
+		h.AssertEq(t, group.Group, []buildpack.GroupBuildpack{
+			{ID: "C", Version: "v1", API: "0.5"},
+			{ID: "B", Version: "v1", API: api.Buildpack.Latest().String()},
+		})
+		h.AssertEq(t, plan.Entries, []platform.BuildPlanEntry{{
+			Providers: []buildpack.GroupBuildpack{{ID: "C", Version: "v1"}},
+			Requires:  []buildpack.Require{{Name: "node"}},
+		}})
+	})
+
+	it("should resolve version ranges against the recorded versions", func() {
+		detector.Store = readStore(`
+[[buildpacks]]
+id = "A"
+version = "1.2.0"
+exit-code = 100
+
+[[buildpacks]]
+id = "A"
+version = "1.10.0"
+
+[[buildpacks]]
+id = "A"
+version = "2.0.0"
+exit-code = 100
+`)
+
+		group, _, err := detector.Detect(buildpack.Order{
+			{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "^1.2"}}},
+		})
+		h.AssertNil(t, err)
+		h.AssertEq(t, group.Group, []buildpack.GroupBuildpack{
+			{ID: "A", Version: "1.10.0", API: api.Buildpack.Latest().String()},
+		})
+	})
+
+	it("should fail when no recorded version matches", func() {
+		detector.Store = readStore("[[buildpacks]]\nid = \"A\"\nversion = \"1.2.0\"\n")
+
+		_, _, err := detector.Detect(buildpack.Order{
+			{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "^2"}}},
+		})
+		h.AssertError(t, err, "no installed version of buildpack 'A' matches '^2'")
+	})
+
+	it("should fail when a buildpack in the order has no recorded result", func() {
+		detector.Store = readStore("[[buildpacks]]\nid = \"A\"\nversion = \"v1\"\n")
+
+		_, _, err := detector.Detect(buildpack.Order{
+			{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "B", Version: "v1"}}},
+		})
+		h.AssertError(t, err, "no simulated detect result for buildpack 'B@v1'")
+	})
+}
@@ -1,8 +1,10 @@
 package lifecycle
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -13,8 +15,8 @@ import (
 )
 
 const (
-	CodeDetectPass = 0
-	CodeDetectFail = 100
+	CodeDetectPass = buildpack.CodeDetectPass
+	CodeDetectFail = buildpack.CodeDetectFail
 )
 
 var (
@@ -28,23 +30,44 @@ type Resolver interface {
 
 type Detector struct {
 	buildpack.DetectConfig
+	Cache    *DetectCache // optional; when set, detect results are reused across builds
 	Platform Platform
+	Report   *platform.DetectReport
 	Resolver Resolver
 	Runs     *sync.Map
 	Store    BuildpackStore
+	RootDirs env.RootDirs // extends the RootDirMap of the detect env
+
+	// Speculate is the number of detect runs that may be started ahead of time for buildpacks in later groups of
+	// the order, while earlier groups are still being detected. Groups are still selected in order, and speculative
+	// runs that are outstanding once a group passes are cancelled. Zero disables speculation.
+	Speculate int
+
+	// Concurrency is the maximum number of detect runs in progress at once, including speculative runs.
+	// Runs for the group being detected take priority: speculative runs only start while none of them are waiting.
+	// Zero means no limit.
+	Concurrency int
+
+	pendingMu sync.Mutex
+	pending   map[string]chan struct{}   // closed when the detect run for the buildpack key is in Runs
+	queued    map[string]*speculativeRun // speculative runs that are waiting for a worker and not yet promoted
+	workers   *workerPool                // limits the detect runs in progress, if Concurrency is set
 }
 
-func NewDetector(config buildpack.DetectConfig, buildpacksDir string, platform Platform) (*Detector, error) {
+func NewDetector(config buildpack.DetectConfig, buildpacksDir string, platformInfo Platform) (*Detector, error) {
+	report := &platform.DetectReport{}
 	resolver := &DefaultResolver{
 		Logger: config.Logger,
+		Report: report,
 	}
-	store, err := buildpack.NewBuildpackStore(buildpacksDir)
+	store, err := buildpack.NewStore(buildpacksDir)
 	if err != nil {
 		return nil, err
 	}
 	return &Detector{
 		DetectConfig: config,
-		Platform:     platform,
+		Platform:     platformInfo,
+		Report:       report,
 		Resolver:     resolver,
 		Runs:         &sync.Map{},
 		Store:        store,
@@ -56,7 +79,22 @@ func (d *Detector) Detect(order buildpack.Order) (buildpack.Group, platform.Buil
 }
 
 func (d *Detector) DetectOrder(order buildpack.Order) (buildpack.Group, platform.BuildPlan, error) {
-	bps, entries, err := d.detectOrder(order, nil, nil, false, &sync.WaitGroup{})
+	if d.Speculate > 0 {
+		parent := d.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithCancel(parent)
+		speculating := &sync.WaitGroup{}
+		speculating.Add(1)
+		go d.speculate(ctx, order, make(chan struct{}, d.Speculate), speculating)
+		defer func() {
+			cancel()
+			speculating.Wait()
+		}()
+	}
+
+	bps, entries, err := d.detectOrder(order, nil, nil, nil, false, &sync.WaitGroup{})
 	if err == ErrBuildpack {
 		err = buildpack.NewLifecycleError(err, buildpack.ErrTypeBuildpack)
 	} else if err == ErrFailedDetection {
@@ -70,12 +108,20 @@ func (d *Detector) DetectOrder(order buildpack.Order) (buildpack.Group, platform
 	return buildpack.Group{Group: bps}, platform.BuildPlan{Entries: entries}, err
 }
 
-func (d *Detector) detectOrder(order buildpack.Order, done, next []buildpack.GroupBuildpack, optional bool, wg *sync.WaitGroup) ([]buildpack.GroupBuildpack, []platform.BuildPlanEntry, error) {
-	ngroup := buildpack.Group{Group: next}
+// groupEntry is a buildpack to be detected, along with the chain of meta-buildpacks that were expanded to reach it.
+type groupEntry struct {
+	buildpack.GroupBuildpack
+	chain []buildpack.GroupBuildpack
+}
+
+func (d *Detector) detectOrder(order buildpack.Order, done []buildpack.GroupBuildpack, next []groupEntry, chain []buildpack.GroupBuildpack, optional bool, wg *sync.WaitGroup) ([]buildpack.GroupBuildpack, []platform.BuildPlanEntry, error) {
 	buildpackErr := false
 	for _, group := range order {
-		// FIXME: double-check slice safety here
-		found, plan, err := d.detectGroup(group.Append(ngroup), done, wg)
+		var entries []groupEntry
+		for _, groupBp := range group.Group {
+			entries = append(entries, groupEntry{GroupBuildpack: groupBp, chain: chain})
+		}
+		found, plan, err := d.detectGroup(append(entries, next...), done, wg)
 		if err == ErrBuildpack {
 			buildpackErr = true
 		}
@@ -86,7 +132,7 @@ func (d *Detector) detectOrder(order buildpack.Order, done, next []buildpack.Gro
 		return found, plan, err
 	}
 	if optional {
-		return d.detectGroup(ngroup, done, wg)
+		return d.detectGroup(next, done, wg)
 	}
 
 	if buildpackErr {
@@ -95,45 +141,230 @@ func (d *Detector) detectOrder(order buildpack.Order, done, next []buildpack.Gro
 	return nil, nil, ErrFailedDetection
 }
 
-func (d *Detector) detectGroup(group buildpack.Group, done []buildpack.GroupBuildpack, wg *sync.WaitGroup) ([]buildpack.GroupBuildpack, []platform.BuildPlanEntry, error) {
-	for i, groupBp := range group.Group {
-		key := groupBp.String()
+func (d *Detector) detectGroup(group []groupEntry, done []buildpack.GroupBuildpack, wg *sync.WaitGroup) ([]buildpack.GroupBuildpack, []platform.BuildPlanEntry, error) {
+	for i, entry := range group {
+		groupBp := entry.GroupBuildpack
 		if hasID(done, groupBp.ID) {
 			continue
 		}
 
-		bp, err := d.Store.Lookup(groupBp.ID, groupBp.Version)
+		resolved, bp, bpDesc, err := d.lookup(groupBp)
 		if err != nil {
 			return nil, nil, err
 		}
-
-		bpDesc := bp.ConfigFile()
-		groupBp.API = bpDesc.API
-		groupBp.Homepage = bpDesc.Buildpack.Homepage
+		if resolved.Version != groupBp.Version {
+			d.Logger.Debugf("Resolved %s to version %s", groupBp, resolved.Version)
+		}
+		groupBp = resolved
 
 		if bpDesc.IsMetaBuildpack() {
+			chain := append(append([]buildpack.GroupBuildpack{}, entry.chain...), groupBp)
+			if err := checkCycle(chain); err != nil {
+				return nil, nil, err
+			}
 			// TODO: double-check slice safety here
-			// FIXME: cyclical references lead to infinite recursion
-			return d.detectOrder(bpDesc.Order, done, group.Group[i+1:], groupBp.Optional, wg)
+			return d.detectOrder(bpDesc.Order, done, group[i+1:], chain, groupBp.Optional, wg)
 		}
 
-		bpEnv := env.NewBuildEnv(os.Environ(), d.Platform, bp)
+		if bpDesc.Source != "" {
+			d.Logger.Debugf("Found %s in %s", groupBp, bpDesc.Source)
+		}
 
 		done = append(done, groupBp)
+		ran, _ := d.startDetect(groupBp, bp, bpDesc, nil)
 		wg.Add(1)
-		go func(key string, bp Buildpack) {
-			if _, ok := d.Runs.Load(key); !ok {
-				d.Runs.Store(key, bp.Detect(&d.DetectConfig, bpEnv))
-			}
+		go func() {
+			<-ran
 			wg.Done()
-		}(key, bp)
+		}()
 	}
 
 	wg.Wait()
 
+	if d.Context != nil && d.Context.Err() != nil {
+		return nil, nil, buildpack.NewLifecycleError(d.Context.Err(), buildpack.ErrTypeInterrupted)
+	}
+
 	return d.Resolver.Resolve(done, d.Runs)
 }
 
+// lookup finds the buildpack in the store and returns the group buildpack with the API, homepage and version
+// (which the order may give as a range) taken from its descriptor.
+func (d *Detector) lookup(groupBp buildpack.GroupBuildpack) (buildpack.GroupBuildpack, Buildpack, *buildpack.Descriptor, error) {
+	bp, err := d.Store.Lookup(groupBp.ID, groupBp.Version)
+	if err != nil {
+		return buildpack.GroupBuildpack{}, nil, nil, err
+	}
+	bpDesc := bp.ConfigFile()
+	groupBp.API = bpDesc.API
+	groupBp.Homepage = bpDesc.Buildpack.Homepage
+	if bpDesc.Buildpack.Version != "" {
+		groupBp.Version = bpDesc.Buildpack.Version
+	}
+	return groupBp, bp, bpDesc, nil
+}
+
+// startDetect starts the detect run for the buildpack unless it has already run or is running. It returns a channel
+// that is closed once the result is in d.Runs, and whether a new run was started.
+// A speculative run is started with a non-nil ctx; if ctx is cancelled during the run, its result is discarded.
+func (d *Detector) startDetect(groupBp buildpack.GroupBuildpack, bp Buildpack, bpDesc *buildpack.Descriptor, ctx context.Context) (<-chan struct{}, bool) {
+	key := groupBp.String()
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	if ran, ok := d.pending[key]; ok {
+		if run, ok := d.queued[key]; ok && ctx == nil {
+			d.workers.promote(run)
+			delete(d.queued, key)
+		}
+		return ran, false
+	}
+	if d.pending == nil {
+		d.pending = map[string]chan struct{}{}
+	}
+	if d.workers == nil && d.Concurrency > 0 {
+		d.workers = newWorkerPool(d.Concurrency)
+	}
+	ran := make(chan struct{})
+	d.pending[key] = ran
+	if _, ok := d.Runs.Load(key); ok {
+		close(ran)
+		return ran, false
+	}
+
+	config := &d.DetectConfig
+	if ctx != nil {
+		specConfig := d.DetectConfig
+		specConfig.Context = ctx
+		config = &specConfig
+	}
+	bpEnv := env.NewBuildEnv(os.Environ(), env.ExtendRootDirMap(env.POSIXBuildEnv, d.RootDirs.Build), d.Platform, bp)
+	bpEnv.BuildpackID = groupBp.ID
+	stackErr := bpDesc.CheckStack(d.StackID, d.BuildMixins)
+	workers := d.workers
+	var queued *speculativeRun
+	if ctx != nil && workers != nil && stackErr == nil {
+		if d.queued == nil {
+			d.queued = map[string]*speculativeRun{}
+		}
+		queued = &speculativeRun{}
+		d.queued[key] = queued
+	}
+	go func() {
+		defer close(ran)
+		var run buildpack.DetectRun
+		if stackErr != nil {
+			run = buildpack.DetectRun{Code: CodeDetectFail, Reason: stackErr.Error()}
+		} else if workers == nil || workers.acquire(ctx, queued) {
+			if queued != nil {
+				d.pendingMu.Lock()
+				delete(d.queued, key)
+				d.pendingMu.Unlock()
+			}
+			if ctx == nil || ctx.Err() == nil {
+				run = d.runDetect(groupBp, bp, bpEnv, config)
+			}
+			if workers != nil {
+				workers.release()
+			}
+		}
+		if ctx != nil && ctx.Err() != nil {
+			d.pendingMu.Lock()
+			delete(d.pending, key)
+			delete(d.queued, key)
+			d.pendingMu.Unlock()
+			return
+		}
+		run.Source = bpDesc.Source
+		d.Runs.Store(key, run)
+	}()
+	return ran, true
+}
+
+// speculate starts detect runs for the buildpacks in the order, expanding meta-buildpacks, with at most cap(slots)
+// runs in progress at once. It stops starting runs when ctx is cancelled.
+func (d *Detector) speculate(ctx context.Context, order buildpack.Order, slots chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	expanded := map[string]bool{}
+	var walk func(order buildpack.Order) bool
+	walk = func(order buildpack.Order) bool {
+		for _, group := range order {
+			for _, groupBp := range group.Group {
+				groupBp, bp, bpDesc, err := d.lookup(groupBp)
+				if err != nil {
+					continue // reported when the group is detected
+				}
+				if bpDesc.IsMetaBuildpack() {
+					if !expanded[groupBp.String()] {
+						expanded[groupBp.String()] = true
+						if !walk(bpDesc.Order) {
+							return false
+						}
+					}
+					continue
+				}
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return false
+				}
+				ran, started := d.startDetect(groupBp, bp, bpDesc, ctx)
+				if !started {
+					<-slots
+					continue
+				}
+				wg.Add(1)
+				go func() {
+					<-ran
+					<-slots
+					wg.Done()
+				}()
+			}
+		}
+		return true
+	}
+	walk(order)
+}
+
+// runDetect runs detect for the buildpack, reusing a previous result from the detect cache when one is configured.
+func (d *Detector) runDetect(groupBp buildpack.GroupBuildpack, bp Buildpack, bpEnv *env.Env, config *buildpack.DetectConfig) buildpack.DetectRun {
+	if d.Cache == nil {
+		return bp.Detect(config, bpEnv)
+	}
+	cacheKey, err := d.Cache.Key(groupBp, bp.ConfigFile().Dir, bpEnv.List())
+	if err != nil {
+		d.Logger.Warnf("Not using detect cache for %s: %s", groupBp, err)
+		return bp.Detect(config, bpEnv)
+	}
+	if run, ok := d.Cache.Load(cacheKey); ok {
+		d.Logger.Debugf("Using cached detect result for %s", groupBp)
+		return run
+	}
+	run := bp.Detect(config, bpEnv)
+	if err := d.Cache.Store(cacheKey, run); err != nil {
+		d.Logger.Warnf("Failed to cache detect result for %s: %s", groupBp, err)
+	}
+	return run
+}
+
+// checkCycle returns an error if the last meta-buildpack in the chain was already expanded earlier in the chain.
+func checkCycle(chain []buildpack.GroupBuildpack) error {
+	last := chain[len(chain)-1]
+	for i, bp := range chain[:len(chain)-1] {
+		if bp.String() != last.String() {
+			continue
+		}
+		var path []string
+		for _, bp := range chain[i:] {
+			path = append(path, bp.String())
+		}
+		return buildpack.NewLifecycleError(
+			errors.Errorf("cyclic meta-buildpack reference: %s", strings.Join(path, " -> ")),
+			buildpack.ErrTypeOrderCycle,
+		)
+	}
+	return nil
+}
+
 func hasID(bps []buildpack.GroupBuildpack, id string) bool {
 	for _, bp := range bps {
 		if bp.ID == id {
@@ -145,11 +376,19 @@ func hasID(bps []buildpack.GroupBuildpack, id string) bool {
 
 type DefaultResolver struct {
 	Logger Logger
+	Report *platform.DetectReport // when set, each resolved group is recorded in the report
 }
 
 // Resolve aggregates the detect output for a group of buildpacks and tries to resolve a build plan for the group.
 // If any required buildpack in the group failed detection or a build plan cannot be resolved, it returns an error.
 func (r *DefaultResolver) Resolve(done []buildpack.GroupBuildpack, detectRuns *sync.Map) ([]buildpack.GroupBuildpack, []platform.BuildPlanEntry, error) {
+	report := &platform.DetectGroupReport{}
+	if r.Report != nil {
+		defer func() {
+			r.Report.Groups = append(r.Report.Groups, *report)
+		}()
+	}
+
 	var groupRuns []buildpack.DetectRun
 	for _, bp := range done {
 		t, ok := detectRuns.Load(bp.String())
@@ -183,42 +422,92 @@ func (r *DefaultResolver) Resolve(done []buildpack.GroupBuildpack, detectRuns *s
 	buildpackErr := false
 	for i, bp := range done {
 		run := groupRuns[i]
+		bpReport := platform.DetectBuildpackReport{
+			ID:         bp.ID,
+			Version:    bp.Version,
+			Optional:   bp.Optional,
+			ExitCode:   run.Code,
+			DurationMS: run.Duration.Milliseconds(),
+			Output:     string(run.Output),
+			Source:     run.Source,
+			Reason:     run.Reason,
+		}
+		if run.Err != nil {
+			bpReport.Error = run.Err.Error()
+		}
 		switch run.Code {
 		case CodeDetectPass:
 			r.Logger.Debugf("pass: %s", bp)
+			bpReport.Result = "pass"
 			results = append(results, detectResult{bp, run})
 		case CodeDetectFail:
+			reason := ""
+			if run.Reason != "" {
+				reason = fmt.Sprintf(" (%s)", run.Reason)
+			}
 			if bp.Optional {
-				r.Logger.Debugf("skip: %s", bp)
+				r.Logger.Debugf("skip: %s%s", bp, reason)
+				bpReport.Result = "skip"
 			} else {
-				r.Logger.Debugf("fail: %s", bp)
+				r.Logger.Debugf("fail: %s%s", bp, reason)
+				bpReport.Result = "fail"
 			}
 			detected = detected && bp.Optional
 		case -1:
 			r.Logger.Infof("err:  %s", bp)
+			bpReport.Result = "error"
 			buildpackErr = true
 			detected = detected && bp.Optional
 		default:
 			r.Logger.Infof("err:  %s (%d)", bp, run.Code)
+			bpReport.Result = "error"
 			buildpackErr = true
 			detected = detected && bp.Optional
 		}
+		report.Buildpacks = append(report.Buildpacks, bpReport)
 	}
 	if !detected {
 		if buildpackErr {
+			report.Result = "error"
 			return nil, nil, ErrBuildpack
 		}
+		report.Result = "fail"
 		return nil, nil, ErrFailedDetection
 	}
 
 	i := 0
 	deps, trial, err := results.runTrials(func(trial detectTrial) (depMap, detectTrial, error) {
 		i++
-		return r.runTrial(i, trial)
+		trialReport := platform.DetectTrialReport{Options: trial.report()}
+		deps, trial, err := r.runTrial(i, trial, &trialReport)
+		report.Trials = append(report.Trials, trialReport)
+		return deps, trial, err
+	}, func(prefix detectTrial, reason, name string) {
+		bp := prefix[len(prefix)-1].GroupBuildpack
+		if reason == "requires" {
+			r.Logger.Debugf("Skipping plans... (fail: %s requires %s)", bp, name)
+		} else {
+			r.Logger.Debugf("Skipping plans... (fail: %s provides unused %s)", bp, name)
+		}
+		report.Trials = append(report.Trials, platform.DetectTrialReport{
+			Options: prefix.report(),
+			Rejections: []platform.DetectRejectionReport{{
+				ID:      bp.ID,
+				Version: bp.Version,
+				Action:  "fail",
+				Reason:  reason,
+				Name:    name,
+			}},
+			Result: "fail",
+			Pruned: true,
+		})
 	})
 	if err != nil {
+		report.Result = "fail"
 		return nil, nil, err
 	}
+	report.Result = "pass"
+	report.Selected = trial.report()
 
 	if len(done) != len(trial) {
 		r.Logger.Infof("%d of %d buildpacks participating", len(trial), len(done))
@@ -249,8 +538,32 @@ func (r *DefaultResolver) Resolve(done []buildpack.GroupBuildpack, detectRuns *s
 	return found, plan, nil
 }
 
-func (r *DefaultResolver) runTrial(i int, trial detectTrial) (depMap, detectTrial, error) {
+func (r *DefaultResolver) runTrial(i int, trial detectTrial, report *platform.DetectTrialReport) (depMap, detectTrial, error) {
+	reject := func(action, reason, name string, bp buildpack.GroupBuildpack) {
+		report.Rejections = append(report.Rejections, platform.DetectRejectionReport{
+			ID:      bp.ID,
+			Version: bp.Version,
+			Action:  action,
+			Reason:  reason,
+			Name:    name,
+		})
+	}
+
+	rejectConflict := func(action, name string, conflict versionConflict) {
+		report.Rejections = append(report.Rejections, platform.DetectRejectionReport{
+			ID:         conflict.bp.ID,
+			Version:    conflict.bp.Version,
+			Action:     action,
+			Reason:     "version-conflict",
+			Name:       name,
+			Constraint: conflict.require.VersionConstraint(),
+			Provider:   conflict.provider.bp.String(),
+			Provided:   conflict.provider.provide.Version,
+		})
+	}
+
 	r.Logger.Debugf("Resolving plan... (try #%d)", i)
+	report.Result = "fail"
 
 	var deps depMap
 	retry := true
@@ -262,22 +575,41 @@ func (r *DefaultResolver) runTrial(i int, trial detectTrial) (depMap, detectTria
 			retry = true
 			if !bp.Optional {
 				r.Logger.Debugf("fail: %s requires %s", bp, name)
+				reject("fail", "requires", name, bp)
 				return ErrFailedDetection
 			}
 			r.Logger.Debugf("skip: %s requires %s", bp, name)
+			reject("skip", "requires", name, bp)
 			trial = trial.remove(bp)
 			return nil
 		}); err != nil {
 			return nil, nil, err
 		}
 
+		if err := deps.eachConflict(func(name string, conflict versionConflict) error {
+			retry = true
+			if !conflict.bp.Optional {
+				r.Logger.Debugf("fail: %s", conflict)
+				rejectConflict("fail", name, conflict)
+				return ErrFailedDetection
+			}
+			r.Logger.Debugf("skip: %s", conflict)
+			rejectConflict("skip", name, conflict)
+			trial = trial.remove(conflict.bp)
+			return nil
+		}); err != nil {
+			return nil, nil, err
+		}
+
 		if err := deps.eachUnmetProvide(func(name string, bp buildpack.GroupBuildpack) error {
 			retry = true
 			if !bp.Optional {
 				r.Logger.Debugf("fail: %s provides unused %s", bp, name)
+				reject("fail", "provides-unused", name, bp)
 				return ErrFailedDetection
 			}
 			r.Logger.Debugf("skip: %s provides unused %s", bp, name)
+			reject("skip", "provides-unused", name, bp)
 			trial = trial.remove(bp)
 			return nil
 		}); err != nil {
@@ -287,8 +619,13 @@ func (r *DefaultResolver) runTrial(i int, trial detectTrial) (depMap, detectTria
 
 	if len(trial) == 0 {
 		r.Logger.Debugf("fail: no viable buildpacks in group")
+		report.Rejections = append(report.Rejections, platform.DetectRejectionReport{
+			Action: "fail",
+			Reason: "no-viable-buildpacks",
+		})
 		return nil, nil, ErrFailedDetection
 	}
+	report.Result = "pass"
 	return deps, trial, nil
 }
 
@@ -302,7 +639,7 @@ func (r *detectResult) options() []detectOption {
 	for i, sections := range append([]buildpack.PlanSections{r.PlanSections}, r.Or...) {
 		bp := r.GroupBuildpack
 		bp.Optional = bp.Optional && i == len(r.Or)
-		out = append(out, detectOption{bp, sections})
+		out = append(out, detectOption{bp, sections, i})
 	}
 	return out
 }
@@ -310,34 +647,28 @@ func (r *detectResult) options() []detectOption {
 type detectResults []detectResult
 type trialFunc func(detectTrial) (depMap, detectTrial, error)
 
-func (rs detectResults) runTrials(f trialFunc) (depMap, detectTrial, error) {
-	return rs.runTrialsFrom(nil, f)
-}
-
-func (rs detectResults) runTrialsFrom(prefix detectTrial, f trialFunc) (depMap, detectTrial, error) {
-	if len(rs) == 0 {
-		deps, trial, err := f(prefix)
-		return deps, trial, err
-	}
-
-	var lastErr error
-	for _, option := range rs[0].options() {
-		deps, trial, err := rs[1:].runTrialsFrom(append(prefix, option), f)
-		if err == nil {
-			return deps, trial, nil
-		}
-		lastErr = err
-	}
-	return nil, nil, lastErr
+// runTrials calls f for each combination of plan alternatives, in order, until one succeeds.
+// Combinations that cannot succeed are skipped without calling f; prune is called with the partial trial instead.
+func (rs detectResults) runTrials(f trialFunc, prune pruneFunc) (depMap, detectTrial, error) {
+	return newPlanSolver(rs, f, prune).solve()
 }
 
 type detectOption struct {
 	buildpack.GroupBuildpack
 	buildpack.PlanSections
+	alternative int
 }
 
 type detectTrial []detectOption
 
+func (ts detectTrial) report() []platform.DetectOptionReport {
+	var out []platform.DetectOptionReport
+	for _, t := range ts {
+		out = append(out, platform.DetectOptionReport{ID: t.ID, Version: t.Version, Alternative: t.alternative})
+	}
+	return out
+}
+
 func (ts detectTrial) remove(bp buildpack.GroupBuildpack) detectTrial {
 	var out detectTrial
 	for _, t := range ts {
@@ -352,6 +683,25 @@ type depEntry struct {
 	platform.BuildPlanEntry
 	earlyRequires []buildpack.GroupBuildpack
 	extraProvides []buildpack.GroupBuildpack
+	versions      []providedVersion
+	conflicts     []versionConflict
+}
+
+type providedVersion struct {
+	bp      buildpack.GroupBuildpack
+	provide buildpack.Provide
+}
+
+// versionConflict is a require whose version constraint is not satisfied by an earlier provide.
+type versionConflict struct {
+	bp       buildpack.GroupBuildpack
+	require  buildpack.Require
+	provider providedVersion
+}
+
+func (c versionConflict) String() string {
+	return fmt.Sprintf("%s requires %s %s, but %s provides %s %s",
+		c.bp, c.require.Name, c.require.VersionConstraint(), c.provider.bp, c.provider.provide.Name, c.provider.provide.Version)
 }
 
 type depMap map[string]depEntry
@@ -372,6 +722,9 @@ func newDepMap(trial detectTrial) depMap {
 func (m depMap) provide(bp buildpack.GroupBuildpack, provide buildpack.Provide) {
 	entry := m[provide.Name]
 	entry.extraProvides = append(entry.extraProvides, bp)
+	if provide.Version != "" {
+		entry.versions = append(entry.versions, providedVersion{bp, provide})
+	}
 	m[provide.Name] = entry
 }
 
@@ -382,12 +735,23 @@ func (m depMap) require(bp buildpack.GroupBuildpack, require buildpack.Require)
 
 	if len(entry.Providers) == 0 {
 		entry.earlyRequires = append(entry.earlyRequires, bp)
+	} else if conflict, ok := entry.conflict(bp, require); ok {
+		entry.conflicts = append(entry.conflicts, conflict)
 	} else {
 		entry.Requires = append(entry.Requires, require)
 	}
 	m[require.Name] = entry
 }
 
+func (e depEntry) conflict(bp buildpack.GroupBuildpack, require buildpack.Require) (versionConflict, bool) {
+	for _, p := range e.versions {
+		if !require.SatisfiedBy(p.provide) {
+			return versionConflict{bp: bp, require: require, provider: p}, true
+		}
+	}
+	return versionConflict{}, false
+}
+
 func (m depMap) eachUnmetProvide(f func(name string, bp buildpack.GroupBuildpack) error) error {
 	for name, entry := range m {
 		if len(entry.extraProvides) != 0 {
@@ -401,6 +765,17 @@ func (m depMap) eachUnmetProvide(f func(name string, bp buildpack.GroupBuildpack
 	return nil
 }
 
+func (m depMap) eachConflict(f func(name string, conflict versionConflict) error) error {
+	for name, entry := range m {
+		for _, conflict := range entry.conflicts {
+			if err := f(name, conflict); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (m depMap) eachUnmetRequire(f func(name string, bp buildpack.GroupBuildpack) error) error {
 	for name, entry := range m {
 		if len(entry.earlyRequires) != 0 {
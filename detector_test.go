@@ -1,10 +1,13 @@
 package lifecycle_test
 
 import (
+	"context"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/memory"
@@ -622,6 +625,420 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 				})
 			})
 		})
+
+		it("should not resolve the group when interrupted", func() {
+			bpA1 := testmock.NewMockBuildpack(mockCtrl)
+			buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil)
+			bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"})
+			bpA1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+			bpA1.EXPECT().Detect(gomock.Any(), gomock.Any()).Return(buildpack.DetectRun{Code: -1, Err: context.Canceled})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			detector.Context = ctx
+
+			_, _, err := detector.Detect(buildpack.Order{
+				{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}},
+				{Group: []buildpack.GroupBuildpack{{ID: "B", Version: "v1"}}},
+			})
+			if err, ok := err.(*buildpack.Error); !ok || err.Type != buildpack.ErrTypeInterrupted {
+				t.Fatalf("Unexpected error:\n%s\n", err)
+			}
+		})
+
+		it("should record the version resolved by the store", func() {
+			detector.Logger = &log.Logger{Handler: memory.New()}
+			bpA1 := testmock.NewMockBuildpack(mockCtrl)
+			buildpackStore.EXPECT().Lookup("A", "^1.2").Return(bpA1, nil)
+			bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3", Buildpack: buildpack.Info{ID: "A", Version: "1.4.0"}})
+			bpA1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+			bpA1.EXPECT().Detect(gomock.Any(), gomock.Any())
+
+			resolved := []buildpack.GroupBuildpack{{ID: "A", Version: "1.4.0", API: "0.3"}}
+			resolver.EXPECT().Resolve(resolved, detector.Runs).Return(resolved, []platform.BuildPlanEntry{}, nil)
+
+			group, _, err := detector.Detect(buildpack.Order{
+				{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "^1.2"}}},
+			})
+			h.AssertNil(t, err)
+			h.AssertEq(t, group.Group, resolved)
+			if _, ok := detector.Runs.Load("A@1.4.0"); !ok {
+				t.Fatal("Expected detect run to be stored under the resolved version")
+			}
+		})
+
+		when("speculating", func() {
+			it.Before(func() {
+				detector.Logger = &log.Logger{Handler: memory.New()}
+				detector.Speculate = 2
+			})
+
+			it("should start detect runs for later groups while earlier groups are detected", func() {
+				bStarted := make(chan struct{})
+				bpA1 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil).AnyTimes()
+				bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"}).AnyTimes()
+				bpA1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+				bpA1.EXPECT().Detect(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ *buildpack.DetectConfig, _ buildpack.BuildEnv) buildpack.DetectRun {
+						<-bStarted // B is detected before A finishes
+						return buildpack.DetectRun{Code: 100}
+					},
+				)
+
+				bpB1 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v1").Return(bpB1, nil).AnyTimes()
+				bpB1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"}).AnyTimes()
+				bpB1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+				bpB1.EXPECT().Detect(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ *buildpack.DetectConfig, _ buildpack.BuildEnv) buildpack.DetectRun {
+						close(bStarted)
+						return buildpack.DetectRun{Code: 0}
+					},
+				)
+
+				groupA := []buildpack.GroupBuildpack{{ID: "A", Version: "v1", API: "0.3"}}
+				groupB := []buildpack.GroupBuildpack{{ID: "B", Version: "v1", API: "0.3"}}
+				gomock.InOrder(
+					resolver.EXPECT().Resolve(groupA, detector.Runs).Return(nil, nil, lifecycle.ErrFailedDetection),
+					resolver.EXPECT().Resolve(groupB, detector.Runs).Return(groupB, []platform.BuildPlanEntry{}, nil),
+				)
+
+				group, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}},
+					{Group: []buildpack.GroupBuildpack{{ID: "B", Version: "v1"}}},
+				})
+				h.AssertNil(t, err)
+				h.AssertEq(t, group.Group, groupB)
+			})
+
+			it("should select the first passing group and cancel outstanding runs", func() {
+				bStarted := make(chan struct{})
+				bpA1 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil).AnyTimes()
+				bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"}).AnyTimes()
+				bpA1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+				bpA1.EXPECT().Detect(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ *buildpack.DetectConfig, _ buildpack.BuildEnv) buildpack.DetectRun {
+						<-bStarted
+						return buildpack.DetectRun{Code: 0}
+					},
+				)
+
+				bpB1 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("B", "v1").Return(bpB1, nil).AnyTimes()
+				bpB1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"}).AnyTimes()
+				bpB1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+				bpB1.EXPECT().Detect(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(config *buildpack.DetectConfig, _ buildpack.BuildEnv) buildpack.DetectRun {
+						close(bStarted)
+						<-config.Context.Done() // B never finishes unless cancelled
+						return buildpack.DetectRun{Code: -1, Err: config.Context.Err()}
+					},
+				)
+
+				groupA := []buildpack.GroupBuildpack{{ID: "A", Version: "v1", API: "0.3"}}
+				resolver.EXPECT().Resolve(groupA, detector.Runs).Return(groupA, []platform.BuildPlanEntry{}, nil)
+
+				group, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}},
+					{Group: []buildpack.GroupBuildpack{{ID: "B", Version: "v1"}}},
+				})
+				h.AssertNil(t, err)
+				h.AssertEq(t, group.Group, groupA)
+				if _, ok := detector.Runs.Load("B@v1"); ok {
+					t.Fatal("Expected cancelled speculative run to be discarded")
+				}
+			})
+
+			it("should run each buildpack once and expand meta-buildpacks", func() {
+				bpM1 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("M", "v1").Return(bpM1, nil).AnyTimes()
+				bpM1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{
+					API: "0.3",
+					Order: buildpack.Order{
+						{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "B", Version: "v1"}}},
+					},
+				}).AnyTimes()
+
+				for _, id := range []string{"A", "B", "C"} {
+					bp := testmock.NewMockBuildpack(mockCtrl)
+					buildpackStore.EXPECT().Lookup(id, "v1").Return(bp, nil).AnyTimes()
+					bp.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"}).AnyTimes()
+					bp.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+					bp.EXPECT().Detect(gomock.Any(), gomock.Any()).Return(buildpack.DetectRun{Code: 100}).Times(1)
+				}
+
+				resolver.EXPECT().Resolve(gomock.Any(), detector.Runs).Return(nil, nil, lifecycle.ErrFailedDetection).Times(3)
+
+				_, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}},
+					{Group: []buildpack.GroupBuildpack{{ID: "M", Version: "v1"}}},
+					{Group: []buildpack.GroupBuildpack{{ID: "C", Version: "v1"}, {ID: "A", Version: "v1"}}},
+				})
+				if err, ok := err.(*buildpack.Error); !ok || err.Type != buildpack.ErrTypeFailedDetection {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+			})
+		})
+
+		when("the number of concurrent detect runs is limited", func() {
+			var (
+				running, maxRunning int32
+				order               buildpack.Order
+			)
+
+			it.Before(func() {
+				running, maxRunning = 0, 0
+				runs := map[string]buildpack.DetectRun{
+					"A": {Code: 100},
+					"B": {Code: 0},
+					"C": {Code: 0, BuildPlan: buildpack.BuildPlan{PlanSections: buildpack.PlanSections{
+						Requires: []buildpack.Require{{Name: "dep"}},
+					}}},
+					"D": {Code: 0, BuildPlan: buildpack.BuildPlan{PlanSections: buildpack.PlanSections{
+						Provides: []buildpack.Provide{{Name: "dep"}},
+						Requires: []buildpack.Require{{Name: "dep"}},
+					}}},
+					"E": {Code: 100},
+					"F": {Code: 0},
+				}
+				for id, run := range runs {
+					run := run
+					bp := testmock.NewMockBuildpack(mockCtrl)
+					buildpackStore.EXPECT().Lookup(id, "v1").Return(bp, nil).AnyTimes()
+					bp.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"}).AnyTimes()
+					bp.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+					bp.EXPECT().Detect(gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ *buildpack.DetectConfig, _ buildpack.BuildEnv) buildpack.DetectRun {
+							n := atomic.AddInt32(&running, 1)
+							for {
+								max := atomic.LoadInt32(&maxRunning)
+								if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+									break
+								}
+							}
+							time.Sleep(5 * time.Millisecond)
+							atomic.AddInt32(&running, -1)
+							return run
+						},
+					).AnyTimes()
+				}
+				bpM := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("M", "v1").Return(bpM, nil).AnyTimes()
+				bpM.EXPECT().ConfigFile().Return(&buildpack.Descriptor{
+					API: "0.3",
+					Order: buildpack.Order{
+						{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "F", Version: "v1"}}},
+						{Group: []buildpack.GroupBuildpack{{ID: "D", Version: "v1"}, {ID: "E", Version: "v1", Optional: true}, {ID: "C", Version: "v1"}}},
+					},
+				}).AnyTimes()
+
+				order = buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "B", Version: "v1"}}},
+					{Group: []buildpack.GroupBuildpack{{ID: "B", Version: "v1", Optional: true}, {ID: "M", Version: "v1"}, {ID: "F", Version: "v1"}}},
+				}
+			})
+
+			detect := func(concurrency, speculate int) (buildpack.Group, platform.BuildPlan) {
+				t.Helper()
+				logger := &log.Logger{Handler: memory.New()}
+				d := &lifecycle.Detector{
+					DetectConfig: buildpack.DetectConfig{Logger: logger},
+					Platform:     platformInt,
+					Resolver:     &lifecycle.DefaultResolver{Logger: logger},
+					Runs:         &sync.Map{},
+					Store:        buildpackStore,
+					Concurrency:  concurrency,
+					Speculate:    speculate,
+				}
+				group, plan, err := d.Detect(order)
+				h.AssertNil(t, err)
+				return group, plan
+			}
+
+			it("should not exceed the limit", func() {
+				for _, limit := range []int{1, 2} {
+					atomic.StoreInt32(&maxRunning, 0)
+					detect(limit, 4)
+					if max := atomic.LoadInt32(&maxRunning); max > int32(limit) {
+						t.Fatalf("Expected at most %d concurrent detect runs, got %d", limit, max)
+					}
+				}
+			})
+
+			it("should select the same group and plan regardless of the limit", func() {
+				group, plan := detect(0, 0)
+				h.AssertEq(t, group.Group, []buildpack.GroupBuildpack{
+					{ID: "B", Version: "v1", API: "0.3"},
+					{ID: "D", Version: "v1", API: "0.3"},
+					{ID: "C", Version: "v1", API: "0.3"},
+					{ID: "F", Version: "v1", API: "0.3"},
+				})
+				for _, concurrency := range []int{1, 2, 8} {
+					for _, speculate := range []int{0, 1, 4} {
+						otherGroup, otherPlan := detect(concurrency, speculate)
+						h.AssertEq(t, otherGroup, group)
+						h.AssertEq(t, otherPlan, plan)
+					}
+				}
+			})
+		})
+
+		it("should fail buildpacks that do not support the stack without running detect", func() {
+			detector.Logger = &log.Logger{Handler: memory.New()}
+			detector.StackID = "io.buildpacks.stacks.focal"
+			detector.BuildMixins = []string{"curl"}
+
+			bpA1 := testmock.NewMockBuildpack(mockCtrl)
+			buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil)
+			bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{
+				API:    "0.3",
+				Stacks: []buildpack.Stack{{ID: "io.buildpacks.stacks.bionic"}},
+			})
+			bpA1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+
+			bpB1 := testmock.NewMockBuildpack(mockCtrl)
+			buildpackStore.EXPECT().Lookup("B", "v1").Return(bpB1, nil)
+			bpB1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{
+				API:    "0.3",
+				Stacks: []buildpack.Stack{{ID: "io.buildpacks.stacks.focal", Mixins: []string{"curl"}}},
+			})
+			bpB1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+			bpB1.EXPECT().Detect(gomock.Any(), gomock.Any())
+
+			group := []buildpack.GroupBuildpack{{ID: "A", Version: "v1", API: "0.3"}, {ID: "B", Version: "v1", API: "0.3"}}
+			resolver.EXPECT().Resolve(group, detector.Runs).Return(nil, nil, lifecycle.ErrFailedDetection)
+
+			_, _, err := detector.Detect(buildpack.Order{{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "B", Version: "v1"}}}})
+			if err, ok := err.(*buildpack.Error); !ok || err.Type != buildpack.ErrTypeFailedDetection {
+				t.Fatalf("Unexpected error:\n%s\n", err)
+			}
+			run, ok := detector.Runs.Load("A@v1")
+			if !ok {
+				t.Fatal("Expected detect run")
+			}
+			h.AssertEq(t, run.(buildpack.DetectRun).Code, lifecycle.CodeDetectFail)
+			h.AssertEq(t, run.(buildpack.DetectRun).Reason, "incompatible stack: 'io.buildpacks.stacks.focal' is not one of the supported stacks (io.buildpacks.stacks.bionic)")
+		})
+
+		it("should record where each buildpack was found", func() {
+			detector.Logger = &log.Logger{Handler: memory.New()}
+			bpA1 := testmock.NewMockBuildpack(mockCtrl)
+			buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil)
+			bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3", Source: "/private/buildpacks"})
+			bpA1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+			bpA1.EXPECT().Detect(gomock.Any(), gomock.Any())
+
+			group := []buildpack.GroupBuildpack{{ID: "A", Version: "v1", API: "0.3"}}
+			resolver.EXPECT().Resolve(group, detector.Runs).Return(group, []platform.BuildPlanEntry{}, nil)
+
+			_, _, err := detector.Detect(buildpack.Order{{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}}})
+			h.AssertNil(t, err)
+			run, ok := detector.Runs.Load("A@v1")
+			if !ok {
+				t.Fatal("Expected detect run")
+			}
+			h.AssertEq(t, run.(buildpack.DetectRun).Source, "/private/buildpacks")
+		})
+
+		when("meta-buildpack cycles", func() {
+			var metaDescriptor = func(refs ...buildpack.GroupBuildpack) *buildpack.Descriptor {
+				return &buildpack.Descriptor{
+					API:   "0.3",
+					Order: []buildpack.Group{{Group: refs}},
+				}
+			}
+
+			var expectCycleError = func(err error, path string) {
+				t.Helper()
+				bpErr, ok := err.(*buildpack.Error)
+				if !ok || bpErr.Type != buildpack.ErrTypeOrderCycle {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+				h.AssertEq(t, err.Error(), "cyclic meta-buildpack reference: "+path)
+			}
+
+			it("fails when a meta-buildpack references itself", func() {
+				bpA1 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil).Times(2)
+				bpA1.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "A", Version: "v1"})).Times(2)
+
+				_, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}},
+				})
+				expectCycleError(err, "A@v1 -> A@v1")
+			})
+
+			it("fails when a meta-buildpack transitively references itself", func() {
+				bpA1 := testmock.NewMockBuildpack(mockCtrl)
+				bpB2 := testmock.NewMockBuildpack(mockCtrl)
+				bpC1 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("C", "v1").Return(bpC1, nil)
+				bpC1.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "A", Version: "v1"}))
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil).Times(2)
+				bpA1.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "B", Version: "v2"})).Times(2)
+				buildpackStore.EXPECT().Lookup("B", "v2").Return(bpB2, nil)
+				bpB2.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "A", Version: "v1"}))
+
+				_, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "C", Version: "v1"}}},
+				})
+				expectCycleError(err, "A@v1 -> B@v2 -> A@v1")
+			})
+
+			it("fails when a cycle passes through another version of the same buildpack", func() {
+				bpA1 := testmock.NewMockBuildpack(mockCtrl)
+				bpA2 := testmock.NewMockBuildpack(mockCtrl)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil).Times(2)
+				bpA1.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "A", Version: "v2"})).Times(2)
+				buildpackStore.EXPECT().Lookup("A", "v2").Return(bpA2, nil)
+				bpA2.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "A", Version: "v1"}))
+
+				_, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}},
+				})
+				expectCycleError(err, "A@v1 -> A@v2 -> A@v1")
+			})
+
+			it("does not treat a different version of a meta-buildpack as a cycle", func() {
+				bpA1 := testmock.NewMockBuildpack(mockCtrl)
+				bpA2 := testmock.NewMockBuildpack(mockCtrl)
+				bpA2.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil)
+				bpA1.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "A", Version: "v2"}))
+				buildpackStore.EXPECT().Lookup("A", "v2").Return(bpA2, nil)
+				bpA2.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"})
+				bpA2.EXPECT().Detect(gomock.Any(), gomock.Any())
+
+				group := []buildpack.GroupBuildpack{{ID: "A", Version: "v2", API: "0.3"}}
+				resolver.EXPECT().Resolve(group, detector.Runs).Return(group, []platform.BuildPlanEntry{}, nil)
+
+				_, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}},
+				})
+				h.AssertNil(t, err)
+			})
+
+			it("does not treat a repeated sibling meta-buildpack as a cycle", func() {
+				bpM1 := testmock.NewMockBuildpack(mockCtrl)
+				bpA1 := testmock.NewMockBuildpack(mockCtrl)
+				bpA1.EXPECT().SupportsAssetPackages().Return(true).AnyTimes()
+				buildpackStore.EXPECT().Lookup("M", "v1").Return(bpM1, nil).Times(2)
+				bpM1.EXPECT().ConfigFile().Return(metaDescriptor(buildpack.GroupBuildpack{ID: "A", Version: "v1"})).Times(2)
+				buildpackStore.EXPECT().Lookup("A", "v1").Return(bpA1, nil)
+				bpA1.EXPECT().ConfigFile().Return(&buildpack.Descriptor{API: "0.3"})
+				bpA1.EXPECT().Detect(gomock.Any(), gomock.Any())
+
+				group := []buildpack.GroupBuildpack{{ID: "A", Version: "v1", API: "0.3"}}
+				resolver.EXPECT().Resolve(group, detector.Runs).Return(group, []platform.BuildPlanEntry{}, nil)
+
+				_, _, err := detector.Detect(buildpack.Order{
+					{Group: []buildpack.GroupBuildpack{{ID: "M", Version: "v1"}, {ID: "M", Version: "v1"}}},
+				})
+				h.AssertNil(t, err)
+			})
+		})
 	})
 
 	when("#Resolve", func() {
@@ -682,6 +1099,31 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 			}
 		})
 
+		it("should log and report why a buildpack failed without running detect", func() {
+			resolver.Report = &platform.DetectReport{}
+			group := []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}}
+
+			detectRuns := &sync.Map{}
+			detectRuns.Store("A@v1", buildpack.DetectRun{
+				Code:   100,
+				Reason: "incompatible stack: 'some-stack' is not one of the supported stacks (other-stack)",
+			})
+
+			_, _, err := resolver.Resolve(group, detectRuns)
+			if err != lifecycle.ErrFailedDetection {
+				t.Fatalf("Unexpected error:\n%s\n", err)
+			}
+
+			if s := h.AllLogs(logHandler); !strings.HasSuffix(s,
+				"======== Results ========\n"+
+					"fail: A@v1 (incompatible stack: 'some-stack' is not one of the supported stacks (other-stack))\n",
+			) {
+				t.Fatalf("Unexpected log:\n%s\n", s)
+			}
+			h.AssertEq(t, resolver.Report.Groups[0].Buildpacks[0].Result, "fail")
+			h.AssertEq(t, resolver.Report.Groups[0].Buildpacks[0].Reason, "incompatible stack: 'some-stack' is not one of the supported stacks (other-stack)")
+		})
+
 		it("should fail with specific error if any bp detect fails in an unexpected way", func() {
 			group := []buildpack.GroupBuildpack{
 				{ID: "A", Version: "v1", Optional: false},
@@ -919,8 +1361,7 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 					"skip: A@v1\n"+
 					"pass: B@v1\n"+
 					"pass: C@v1\n"+
-					"Resolving plan... (try #1)\n"+
-					"fail: B@v1 requires dep1\n",
+					"Skipping plans... (fail: B@v1 requires dep1)\n",
 			) {
 				t.Fatalf("Unexpected log:\n%s\n", s)
 			}
@@ -976,8 +1417,7 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 					"pass: A@v1\n"+
 					"pass: B@v1\n"+
 					"skip: C@v1\n"+
-					"Resolving plan... (try #1)\n"+
-					"fail: B@v1 provides unused dep1\n",
+					"Skipping plans... (fail: B@v1 provides unused dep1)\n",
 			) {
 				t.Fatalf("Unexpected log:\n%s\n", s)
 			}
@@ -1170,7 +1610,11 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 			}
 
 			if s := h.AllLogs(logHandler); !strings.HasSuffix(s,
-				"Resolving plan... (try #16)\n"+
+				"Skipping plans... (fail: A@v1 provides unused dep2-missing)\n"+
+					"Skipping plans... (fail: B@v1 requires dep3-missing)\n"+
+					"Skipping plans... (fail: C@v1 requires dep4-missing)\n"+
+					"Skipping plans... (fail: D@v1 requires dep7-missing)\n"+
+					"Resolving plan... (try #1)\n"+
 					"skip: D@v1 requires dep9-missing\n"+
 					"skip: D@v1 provides unused dep10-missing\n"+
 					"3 of 4 buildpacks participating\n"+
@@ -1181,6 +1625,292 @@ func testDetector(t *testing.T, when spec.G, it spec.S) {
 				t.Fatalf("Unexpected log:\n%s\n", s)
 			}
 		})
+
+		when("build plans have versions", func() {
+			it("should fail if a provided version does not satisfy a require's version constraint", func() {
+				group := []buildpack.GroupBuildpack{
+					{ID: "A", Version: "v1"},
+					{ID: "B", Version: "v1"},
+				}
+
+				detectRuns := &sync.Map{}
+				detectRuns.Store("A@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Provides: []buildpack.Provide{{Name: "node", Version: "14.17.0"}},
+						},
+					},
+				})
+				detectRuns.Store("B@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Requires: []buildpack.Require{{Name: "node", Metadata: map[string]interface{}{"version": ">=16"}}},
+						},
+					},
+				})
+
+				report := &platform.DetectReport{}
+				resolver.Report = report
+				_, _, err := resolver.Resolve(group, detectRuns)
+				if err != lifecycle.ErrFailedDetection {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+
+				if s := h.AllLogs(logHandler); !strings.HasSuffix(s,
+					"Resolving plan... (try #1)\n"+
+						"fail: B@v1 requires node >=16, but A@v1 provides node 14.17.0\n",
+				) {
+					t.Fatalf("Unexpected log:\n%s\n", s)
+				}
+
+				h.AssertEq(t, report.Groups[0].Trials[0].Rejections, []platform.DetectRejectionReport{{
+					ID:         "B",
+					Version:    "v1",
+					Action:     "fail",
+					Reason:     "version-conflict",
+					Name:       "node",
+					Constraint: ">=16",
+					Provider:   "A@v1",
+					Provided:   "14.17.0",
+				}})
+			})
+
+			it("should fail if a provided partial version does not satisfy a require's version constraint", func() {
+				group := []buildpack.GroupBuildpack{
+					{ID: "A", Version: "v1"},
+					{ID: "B", Version: "v1"},
+				}
+
+				detectRuns := &sync.Map{}
+				detectRuns.Store("A@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Provides: []buildpack.Provide{{Name: "node", Version: "14"}},
+						},
+					},
+				})
+				detectRuns.Store("B@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Requires: []buildpack.Require{{Name: "node", Metadata: map[string]interface{}{"version": ">=16"}}},
+						},
+					},
+				})
+
+				_, _, err := resolver.Resolve(group, detectRuns)
+				if err != lifecycle.ErrFailedDetection {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+
+				if s := h.AllLogs(logHandler); !strings.HasSuffix(s,
+					"Resolving plan... (try #1)\n"+
+						"fail: B@v1 requires node >=16, but A@v1 provides node 14\n",
+				) {
+					t.Fatalf("Unexpected log:\n%s\n", s)
+				}
+			})
+
+			it("should fallback to an alternate build plan that satisfies version constraints", func() {
+				group := []buildpack.GroupBuildpack{
+					{ID: "A", Version: "v1"},
+					{ID: "B", Version: "v1"},
+				}
+
+				detectRuns := &sync.Map{}
+				detectRuns.Store("A@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Provides: []buildpack.Provide{{Name: "node", Version: "14.17.0"}},
+						},
+						Or: []buildpack.PlanSections{
+							{Provides: []buildpack.Provide{{Name: "node", Version: "16.3.0"}}},
+						},
+					},
+				})
+				detectRuns.Store("B@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Requires: []buildpack.Require{{Name: "node", Metadata: map[string]interface{}{"version": "^16.1"}}},
+						},
+					},
+				})
+
+				found, entries, err := resolver.Resolve(group, detectRuns)
+				if err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+
+				if s := cmp.Diff(found, group); s != "" {
+					t.Fatalf("Unexpected group:\n%s\n", s)
+				}
+
+				if !hasEntries(entries, []platform.BuildPlanEntry{
+					{
+						Providers: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}},
+						Requires:  []buildpack.Require{{Name: "node", Metadata: map[string]interface{}{"version": "^16.1"}}},
+					},
+				}) {
+					t.Fatalf("Unexpected entries:\n%+v\n", entries)
+				}
+			})
+
+			it("should ignore versions that are not semantic versions", func() {
+				group := []buildpack.GroupBuildpack{
+					{ID: "A", Version: "v1"},
+					{ID: "B", Version: "v1"},
+				}
+
+				detectRuns := &sync.Map{}
+				detectRuns.Store("A@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Provides: []buildpack.Provide{{Name: "jdk", Version: "11.0.11+9"}},
+						},
+					},
+				})
+				detectRuns.Store("B@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Requires: []buildpack.Require{{Name: "jdk", Metadata: map[string]interface{}{"version": "latest"}}},
+						},
+					},
+				})
+
+				_, _, err := resolver.Resolve(group, detectRuns)
+				if err != nil {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+			})
+		})
+
+		when("a report is provided", func() {
+			var report *platform.DetectReport
+
+			it.Before(func() {
+				report = &platform.DetectReport{}
+				resolver.Report = report
+			})
+
+			it("should record detect results and the chosen alternatives for a passing group", func() {
+				group := []buildpack.GroupBuildpack{
+					{ID: "A", Version: "v1"},
+					{ID: "B", Version: "v1", Optional: true},
+					{ID: "C", Version: "v1"},
+				}
+
+				detectRuns := &sync.Map{}
+				detectRuns.Store("A@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Provides: []buildpack.Provide{{Name: "dep1-missing"}},
+						},
+						Or: []buildpack.PlanSections{
+							{Provides: []buildpack.Provide{{Name: "dep2-present"}}},
+						},
+					},
+					Output:   []byte("detected A"),
+					Duration: 2 * time.Second,
+				})
+				detectRuns.Store("B@v1", buildpack.DetectRun{Code: 100})
+				detectRuns.Store("C@v1", buildpack.DetectRun{
+					BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{
+							Requires: []buildpack.Require{{Name: "dep2-present"}},
+						},
+					},
+				})
+
+				_, _, err := resolver.Resolve(group, detectRuns)
+				h.AssertNil(t, err)
+
+				if s := cmp.Diff(report.Groups, []platform.DetectGroupReport{
+					{
+						Result: "pass",
+						Buildpacks: []platform.DetectBuildpackReport{
+							{ID: "A", Version: "v1", Result: "pass", DurationMS: 2000, Output: "detected A"},
+							{ID: "B", Version: "v1", Optional: true, Result: "skip", ExitCode: 100},
+							{ID: "C", Version: "v1", Result: "pass"},
+						},
+						Trials: []platform.DetectTrialReport{
+							{
+								Options: []platform.DetectOptionReport{
+									{ID: "A", Version: "v1", Alternative: 0},
+								},
+								Rejections: []platform.DetectRejectionReport{
+									{ID: "A", Version: "v1", Action: "fail", Reason: "provides-unused", Name: "dep1-missing"},
+								},
+								Result: "fail",
+								Pruned: true,
+							},
+							{
+								Options: []platform.DetectOptionReport{
+									{ID: "A", Version: "v1", Alternative: 1},
+									{ID: "C", Version: "v1", Alternative: 0},
+								},
+								Result: "pass",
+							},
+						},
+						Selected: []platform.DetectOptionReport{
+							{ID: "A", Version: "v1", Alternative: 1},
+							{ID: "C", Version: "v1", Alternative: 0},
+						},
+					},
+				}); s != "" {
+					t.Fatalf("Unexpected report:\n%s\n", s)
+				}
+			})
+
+			it("should record why a group failed", func() {
+				group := []buildpack.GroupBuildpack{
+					{ID: "A", Version: "v1"},
+					{ID: "B", Version: "v1"},
+				}
+
+				detectRuns := &sync.Map{}
+				detectRuns.Store("A@v1", buildpack.DetectRun{Code: 100})
+				detectRuns.Store("B@v1", buildpack.DetectRun{Code: -1, Err: errors.New("some error")})
+
+				_, _, err := resolver.Resolve(group, detectRuns)
+				if err != lifecycle.ErrBuildpack {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+
+				if s := cmp.Diff(report.Groups, []platform.DetectGroupReport{
+					{
+						Result: "error",
+						Buildpacks: []platform.DetectBuildpackReport{
+							{ID: "A", Version: "v1", Result: "fail", ExitCode: 100},
+							{ID: "B", Version: "v1", Result: "error", ExitCode: -1, Error: "some error"},
+						},
+					},
+				}); s != "" {
+					t.Fatalf("Unexpected report:\n%s\n", s)
+				}
+			})
+
+			it("should record when no buildpacks are viable", func() {
+				_, _, err := resolver.Resolve([]buildpack.GroupBuildpack{}, &sync.Map{})
+				if err != lifecycle.ErrFailedDetection {
+					t.Fatalf("Unexpected error:\n%s\n", err)
+				}
+
+				if s := cmp.Diff(report.Groups, []platform.DetectGroupReport{
+					{
+						Result: "fail",
+						Trials: []platform.DetectTrialReport{
+							{
+								Rejections: []platform.DetectRejectionReport{
+									{Action: "fail", Reason: "no-viable-buildpacks"},
+								},
+								Result: "fail",
+							},
+						},
+					},
+				}); s != "" {
+					t.Fatalf("Unexpected report:\n%s\n", s)
+				}
+			})
+		})
 	})
 }
 
@@ -39,18 +39,19 @@ var AssetsEnvVars = []string{
 
 var ignoreEnvVarCase = runtime.GOOS == "windows"
 
-// NewBuildEnv returns a build-time Env from the given environment.
+// NewBuildEnv returns a build-time Env from the given environment, with the given RootDirMap, which is usually
+// POSIXBuildEnv or an extension of it.
 //
-// Keys in the BuildEnvIncludelist will be added to the Environment.
+// Keys in the BuildEnvIncludelist and the RootDirMap will be added to the Environment.
 // If the platform and buildpack support asset packages, keys from AssetsEnvVars will be added to the environment.
-func NewBuildEnv(environ []string, platform Platform, buildpack Buildpack) *Env {
-	envFilter := isNotMember(BuildEnvIncludelist, flattenMap(POSIXBuildEnv))
+func NewBuildEnv(environ []string, rootDirMap map[string][]string, platform Platform, buildpack Buildpack) *Env {
+	envFilter := isNotMember(BuildEnvIncludelist, flattenMap(rootDirMap))
 	if platform.SupportsAssetPackages() && buildpack.SupportsAssetPackages() {
-		envFilter = isNotMember(BuildEnvIncludelist, flattenMap(POSIXBuildEnv), AssetsEnvVars)
+		envFilter = isNotMember(BuildEnvIncludelist, flattenMap(rootDirMap), AssetsEnvVars)
 	}
 
 	return &Env{
-		RootDirMap: POSIXBuildEnv,
+		RootDirMap: rootDirMap,
 		Vars:       varsFromEnv(environ, ignoreEnvVarCase, envFilter),
 	}
 }
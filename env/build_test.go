@@ -57,7 +57,7 @@ func testBuildEnv(t *testing.T, when spec.G, it spec.S) {
 				"LIBRARY_PATH=some-library-path",
 				"CPATH=some-cpath",
 				"PKG_CONFIG_PATH=some-pkg-config-path",
-			}, platform, buildpack)
+			}, env.POSIXBuildEnv, platform, buildpack)
 			out := benv.List()
 			sort.Strings(out)
 			expectedVars := []string{
@@ -93,7 +93,7 @@ func testBuildEnv(t *testing.T, when spec.G, it spec.S) {
 
 			benv := env.NewBuildEnv([]string{
 				"CNB_STACK_ID=included=true",
-			}, platform, buildpack)
+			}, env.POSIXBuildEnv, platform, buildpack)
 			if s := cmp.Diff(benv.List(), []string{
 				"CNB_STACK_ID=included=true",
 			}); s != "" {
@@ -105,12 +105,32 @@ func testBuildEnv(t *testing.T, when spec.G, it spec.S) {
 			platform.EXPECT().SupportsAssetPackages().Return(true)
 			buildpack.EXPECT().SupportsAssetPackages().Return(true)
 
-			benv := env.NewBuildEnv([]string{}, platform, buildpack)
+			benv := env.NewBuildEnv([]string{}, env.POSIXBuildEnv, platform, buildpack)
 			if s := cmp.Diff(benv.RootDirMap, env.POSIXBuildEnv); s != "" {
 				t.Fatalf("Unexpected root dir map\n%s\n", s)
 			}
 		})
 
+		it("includes the vars of an extended root dir map", func() {
+			platform.EXPECT().SupportsAssetPackages().Return(true)
+			buildpack.EXPECT().SupportsAssetPackages().Return(true)
+
+			rootDirMap := env.ExtendRootDirMap(env.POSIXBuildEnv, map[string][]string{"lib/python3/site-packages": {"PYTHONPATH"}})
+			benv := env.NewBuildEnv([]string{
+				"PATH=some-path",
+				"PYTHONPATH=some-python-path",
+				"NOT_INCLUDED=not-included",
+			}, rootDirMap, platform, buildpack)
+			out := benv.List()
+			sort.Strings(out)
+			if s := cmp.Diff(out, []string{"PATH=some-path", "PYTHONPATH=some-python-path"}); s != "" {
+				t.Fatalf("Unexpected env\n%s\n", s)
+			}
+			if s := cmp.Diff(benv.RootDirMap, rootDirMap); s != "" {
+				t.Fatalf("Unexpected root dir map\n%s\n", s)
+			}
+		})
+
 		when("asset packages", func() {
 			when("supported by platform", func() {
 				it.Before(func() {
@@ -123,7 +143,7 @@ func testBuildEnv(t *testing.T, when spec.G, it spec.S) {
 					})
 
 					it("includes CNB_ASSETS", func() {
-						foundEnv := env.NewBuildEnv([]string{"CNB_ASSETS=some-assets-path"}, platform, buildpack).List()
+						foundEnv := env.NewBuildEnv([]string{"CNB_ASSETS=some-assets-path"}, env.POSIXBuildEnv, platform, buildpack).List()
 						h.AssertContains(t, foundEnv, "CNB_ASSETS=some-assets-path")
 					})
 				})
@@ -134,7 +154,7 @@ func testBuildEnv(t *testing.T, when spec.G, it spec.S) {
 					})
 
 					it("excludes CNB_ASSETS", func() {
-						foundEnv := env.NewBuildEnv([]string{"CNB_ASSETS=some-assets-path"}, platform, buildpack).List()
+						foundEnv := env.NewBuildEnv([]string{"CNB_ASSETS=some-assets-path"}, env.POSIXBuildEnv, platform, buildpack).List()
 						var expectedEnv []string
 						h.AssertEq(t, foundEnv, expectedEnv)
 					})
@@ -147,7 +167,7 @@ func testBuildEnv(t *testing.T, when spec.G, it spec.S) {
 				})
 
 				it("excludes CNB_ASSETS", func() {
-					foundEnv := env.NewBuildEnv([]string{"CNB_ASSETS=some-assets-path"}, platform, buildpack).List()
+					foundEnv := env.NewBuildEnv([]string{"CNB_ASSETS=some-assets-path"}, env.POSIXBuildEnv, platform, buildpack).List()
 					var expectedEnv []string
 					h.AssertEq(t, foundEnv, expectedEnv)
 				})
@@ -167,7 +187,7 @@ func testBuildEnv(t *testing.T, when spec.G, it spec.S) {
 
 				benv := env.NewBuildEnv([]string{
 					"Path=some-path",
-				}, platform, buildpack)
+				}, env.POSIXBuildEnv, platform, buildpack)
 				out := benv.List()
 				h.AssertEq(t, len(out), 1)
 				h.AssertEq(t, out[0], "PATH=some-path")
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -16,17 +17,28 @@ type Env struct {
 	// RootDirMap maps directories in a posix root filesystem to a slice of environment variables that
 	RootDirMap map[string][]string
 	Vars       *Vars
+	Trace      *Trace // when set, every modification of Vars is recorded in the trace
+	// BuildpackID, when set, scopes the env to a buildpack: WithPlatform also applies the platform env files in the
+	// <escaped-buildpack-id> subdirectory of the platform env dir.
+	BuildpackID string
 }
 
 // AddRootDir modifies the environment given a root dir. If the root dir contains a directory that matches a key in
 // the Env RooDirMap, the absolute path to the keyed directory will be prepended to all the associated environment variables
-// using the OS path list separator as a delimiter.
+// using the OS path list separator as a delimiter. Keyed directories are applied in order of name, so that a variable
+// associated with several directories has a stable value.
 func (p *Env) AddRootDir(dir string) error {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return err
 	}
-	for dir, vars := range p.RootDirMap {
+	var dirs []string
+	for dir := range p.RootDirMap {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		vars := p.RootDirMap[dir]
 		childDir := filepath.Join(absDir, dir)
 		if _, err := os.Stat(childDir); os.IsNotExist(err) {
 			continue
@@ -34,7 +46,7 @@ func (p *Env) AddRootDir(dir string) error {
 			return err
 		}
 		for _, key := range vars {
-			p.Vars.Set(key, childDir+prefix(p.Vars.Get(key), os.PathListSeparator))
+			p.Trace.set(p.Vars, key, childDir+prefix(p.Vars.Get(key), os.PathListSeparator), p.modification(TraceActionRootDir, childDir, filepath.Base(absDir)))
 		}
 	}
 	return nil
@@ -83,20 +95,23 @@ func (p *Env) AddEnvDir(envDir string, defaultAction ActionType) error {
 		} else {
 			action = defaultAction
 		}
+		mod := p.modification(string(action), filepath.Join(envDir, k), envDirLayer(envDir))
 		switch action {
 		case ActionTypePrepend:
-			p.Vars.Set(name, v+prefix(p.Vars.Get(name), delim(envDir, name)...))
+			p.Trace.set(p.Vars, name, v+prefix(p.Vars.Get(name), delim(envDir, name)...), mod)
 		case ActionTypeAppend:
-			p.Vars.Set(name, suffix(p.Vars.Get(name), delim(envDir, name)...)+v)
+			p.Trace.set(p.Vars, name, suffix(p.Vars.Get(name), delim(envDir, name)...)+v, mod)
 		case ActionTypeOverride:
-			p.Vars.Set(name, v)
+			p.Trace.set(p.Vars, name, v, mod)
 		case ActionTypeDefault:
 			if p.Vars.Get(name) != "" {
+				p.Trace.skip(p.Vars, name, mod)
 				return nil
 			}
-			p.Vars.Set(name, v)
+			p.Trace.set(p.Vars, name, v, mod)
 		case ActionTypePrependPath:
-			p.Vars.Set(name, v+prefix(p.Vars.Get(name), delim(envDir, name, os.PathListSeparator)...))
+			mod.Action = TraceActionPrependPath
+			p.Trace.set(p.Vars, name, v+prefix(p.Vars.Get(name), delim(envDir, name, os.PathListSeparator)...), mod)
 		}
 		return nil
 	}); err != nil {
@@ -107,7 +122,20 @@ func (p *Env) AddEnvDir(envDir string, defaultAction ActionType) error {
 
 // Set sets the environment variable with the given name to the given value.
 func (p *Env) Set(name, v string) {
-	p.Vars.Set(name, v)
+	mod := Modification{Action: TraceActionSet}
+	if p.Trace != nil {
+		mod = p.modification(TraceActionSet, p.Trace.Source, p.Trace.Layer)
+	}
+	p.Trace.set(p.Vars, name, v, mod)
+}
+
+// modification returns a Modification attributed to the buildpack being traced.
+func (p *Env) modification(action, source, layer string) Modification {
+	mod := Modification{Action: action, Source: source, Layer: layer}
+	if p.Trace != nil {
+		mod.Buildpack = p.Trace.Buildpack
+	}
+	return mod
 }
 
 // WithPlatform returns the environment after applying modifications from the given platform dir.
@@ -115,22 +143,36 @@ func (p *Env) Set(name, v string) {
 // RootDirMap, the given variable will be set to the contents of the file. If the name does match an environment
 // variable name in the RootDirMap, the contents of the file will be prepended to the environment variable value
 // using the OS path list separator as a delimiter.
+// If the Env has a BuildpackID, the files in the buildpack's subdirectory are then applied in the same way.
+// If the Env is traced, these modifications are recorded although they are not made to the Env itself.
 func (p *Env) WithPlatform(platformDir string) (out []string, err error) {
 	vars := NewVars(p.Vars.vals, p.Vars.ignoreCase)
 
-	if err := eachEnvFile(filepath.Join(platformDir, "env"), func(k, v string) error {
-		if p.isRootEnv(k) {
-			vars.Set(k, v+prefix(vars.Get(k), os.PathListSeparator))
+	platformEnvDirs := []string{filepath.Join(platformDir, "env")}
+	if p.BuildpackID != "" {
+		platformEnvDirs = append(platformEnvDirs, filepath.Join(platformDir, "env", escapeID(p.BuildpackID)))
+	}
+	for _, platformEnvDir := range platformEnvDirs {
+		if err := eachEnvFile(platformEnvDir, func(k, v string) error {
+			mod := Modification{Action: TraceActionPlatform, Source: filepath.Join(platformEnvDir, k)}
+			if p.isRootEnv(k) {
+				p.Trace.set(vars, k, v+prefix(vars.Get(k), os.PathListSeparator), mod)
+				return nil
+			}
+			p.Trace.set(vars, k, v, mod)
 			return nil
+		}); err != nil {
+			return nil, err
 		}
-		vars.Set(k, v)
-		return nil
-	}); err != nil {
-		return nil, err
 	}
 	return vars.List(), nil
 }
 
+// escapeID escapes a buildpack ID for use as a directory name, as launch.EscapeID does.
+func escapeID(id string) string {
+	return strings.Replace(id, "/", "_", -1)
+}
+
 func prefix(s string, prefix ...byte) string {
 	if s == "" {
 		return ""
@@ -99,6 +99,24 @@ func testEnv(t *testing.T, when spec.G, it spec.S) {
 				t.Fatalf("Unexpected env\n%s\n", s)
 			}
 		})
+		it("should apply directories associated with the same env var in order of name", func() {
+			mkdir(t,
+				filepath.Join(tmpDir, "lib"),
+				filepath.Join(tmpDir, "lib64"),
+			)
+			envv.RootDirMap = map[string][]string{
+				"lib64": {"LD_LIBRARY_PATH"},
+				"lib":   {"LD_LIBRARY_PATH"},
+			}
+			if err := envv.AddRootDir(tmpDir); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+
+			expected := strings.Join([]string{filepath.Join(tmpDir, "lib64"), filepath.Join(tmpDir, "lib")}, string(os.PathListSeparator))
+			if s := cmp.Diff(envv.Get("LD_LIBRARY_PATH"), expected); s != "" {
+				t.Fatalf("Unexpected val:\n%s\n", s)
+			}
+		})
 	})
 
 	when("#AddEnvDir", func() {
@@ -261,6 +279,37 @@ func testEnv(t *testing.T, when spec.G, it spec.S) {
 				t.Fatalf("Unexpected env:\n%s\n", s)
 			}
 		})
+		when("the env has a buildpack ID", func() {
+			it("should apply the buildpack's platform env vars after the global ones", func() {
+				mkdir(t,
+					filepath.Join(tmpDir, "env", "some_buildpack"),
+					filepath.Join(tmpDir, "env", "other_buildpack"),
+				)
+				mkfile(t, "value-path", filepath.Join(tmpDir, "env", "PATH"))
+				mkfile(t, "value-global", filepath.Join(tmpDir, "env", "VAR_OVERRIDE"))
+				mkfile(t, "value-bp-path", filepath.Join(tmpDir, "env", "some_buildpack", "PATH"))
+				mkfile(t, "value-bp", filepath.Join(tmpDir, "env", "some_buildpack", "VAR_OVERRIDE"))
+				mkfile(t, "some-token", filepath.Join(tmpDir, "env", "some_buildpack", "NPM_TOKEN"))
+				mkfile(t, "other-token", filepath.Join(tmpDir, "env", "other_buildpack", "OTHER_TOKEN"))
+
+				envv.Vars = env.NewVars(map[string]string{"PATH": "value-path-orig"}, false)
+				envv.BuildpackID = "some/buildpack"
+				out, err := envv.WithPlatform(tmpDir)
+				if err != nil {
+					t.Fatalf("Error: %s\n", err)
+				}
+				sort.Strings(out)
+
+				expected := []string{
+					formEnv("NPM_TOKEN", "some-token"),
+					formEnv("PATH", "value-bp-path", "value-path", "value-path-orig"),
+					formEnv("VAR_OVERRIDE", "value-bp"),
+				}
+				if s := cmp.Diff(out, expected); s != "" {
+					t.Fatalf("Unexpected env:\n%s\n", s)
+				}
+			})
+		})
 	})
 
 	when("#Get", func() {
@@ -281,6 +330,115 @@ func testEnv(t *testing.T, when spec.G, it spec.S) {
 			}
 		})
 	})
+
+	when("#Trace", func() {
+		var layerDir string
+
+		it.Before(func() {
+			envv.Vars = env.NewVars(map[string]string{"PATH": "path-orig", "JAVA_OPTS": "opts-orig"}, false)
+			envv.Trace = &env.Trace{Buildpack: "some/bp"}
+			layerDir = filepath.Join(tmpDir, "some_bp", "some-layer")
+			mkdir(t,
+				filepath.Join(layerDir, "bin"),
+				filepath.Join(layerDir, "env"),
+				filepath.Join(tmpDir, "platform", "env"),
+			)
+			mkfile(t, "-Xmx1g", filepath.Join(layerDir, "env", "JAVA_OPTS.append"))
+			mkfile(t, " ", filepath.Join(layerDir, "env", "JAVA_OPTS.delim"))
+			mkfile(t, "default-opts", filepath.Join(layerDir, "env", "JAVA_OPTS.default"))
+			mkfile(t, "platform-val", filepath.Join(tmpDir, "platform", "env", "SOME_VAR"))
+		})
+
+		it("should record each modification in order", func() {
+			if err := envv.AddRootDir(layerDir); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			if err := envv.AddEnvDir(filepath.Join(layerDir, "env"), env.ActionTypeOverride); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			envv.Trace.Layer = "some-layer"
+			envv.Trace.Source = "some-exec.d"
+			envv.Set("SOME_VAR", "set-val")
+			for i := 0; i < 2; i++ {
+				if _, err := envv.WithPlatform(filepath.Join(tmpDir, "platform")); err != nil {
+					t.Fatalf("Error: %s\n", err)
+				}
+			}
+
+			binDir := filepath.Join(layerDir, "bin")
+			expected := []env.VarTrace{
+				{Name: "JAVA_OPTS", Modifications: []env.Modification{
+					{Action: env.TraceActionInitial, Value: "opts-orig"},
+					{Action: "append", Source: filepath.Join(layerDir, "env", "JAVA_OPTS.append"), Layer: "some-layer", Buildpack: "some/bp", Value: "opts-orig -Xmx1g"},
+					{Action: "default", Source: filepath.Join(layerDir, "env", "JAVA_OPTS.default"), Layer: "some-layer", Buildpack: "some/bp", Value: "opts-orig -Xmx1g", Skipped: true},
+				}},
+				{Name: "PATH", Modifications: []env.Modification{
+					{Action: env.TraceActionInitial, Value: "path-orig"},
+					{Action: env.TraceActionRootDir, Source: binDir, Layer: "some-layer", Buildpack: "some/bp", Value: binDir + string(os.PathListSeparator) + "path-orig"},
+				}},
+				{Name: "SOME_VAR", Modifications: []env.Modification{
+					{Action: env.TraceActionSet, Source: "some-exec.d", Layer: "some-layer", Buildpack: "some/bp", Value: "set-val"},
+					{Action: env.TraceActionPlatform, Source: filepath.Join(tmpDir, "platform", "env", "SOME_VAR"), Value: "platform-val"},
+				}},
+			}
+			if s := cmp.Diff(envv.Trace.Vars(), expected); s != "" {
+				t.Fatalf("Unexpected trace:\n%s\n", s)
+			}
+		})
+
+		it("should explain the value of each modified variable", func() {
+			if err := envv.AddEnvDir(filepath.Join(layerDir, "env"), env.ActionTypeOverride); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			out := &strings.Builder{}
+			if err := envv.Trace.Explain(out, envv.List()); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+
+			source := func(name string) string { return filepath.Join(layerDir, "env", name) }
+			expected := "JAVA_OPTS=opts-orig -Xmx1g\n" +
+				"  initial      \"opts-orig\"\n" +
+				"  append       \"opts-orig -Xmx1g\" (buildpack 'some/bp', layer 'some-layer', from " + source("JAVA_OPTS.append") + ")\n" +
+				"  default      skipped, already set (buildpack 'some/bp', layer 'some-layer', from " + source("JAVA_OPTS.default") + ")\n"
+			if s := cmp.Diff(out.String(), expected); s != "" {
+				t.Fatalf("Unexpected explanation:\n%s\n", s)
+			}
+		})
+
+		it("should explain the values of the env that was executed with", func() {
+			envv.Set("SOME_VAR", "set-val")
+			environ, err := envv.WithPlatform(filepath.Join(tmpDir, "platform"))
+			if err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			out := &strings.Builder{}
+			if err := envv.Trace.Explain(out, environ); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			if !strings.HasPrefix(out.String(), "SOME_VAR=platform-val\n") {
+				t.Fatalf("Unexpected explanation:\n%s\n", out.String())
+			}
+
+			out.Reset()
+			if err := envv.Trace.Explain(out, nil); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			if !strings.HasPrefix(out.String(), "SOME_VAR is not set\n") {
+				t.Fatalf("Unexpected explanation:\n%s\n", out.String())
+			}
+		})
+
+		it("should not record modifications when the env is not traced", func() {
+			envv.Trace = nil
+			if err := envv.AddEnvDir(filepath.Join(layerDir, "env"), env.ActionTypeOverride); err != nil {
+				t.Fatalf("Error: %s\n", err)
+			}
+			envv.Set("SOME_VAR", "set-val")
+			if s := cmp.Diff(envv.Get("JAVA_OPTS"), "opts-orig -Xmx1g"); s != "" {
+				t.Fatalf("Unexpected val:\n%s\n", s)
+			}
+		})
+	})
 }
 
 func formEnv(name string, values ...string) string {
@@ -0,0 +1,69 @@
+package env
+
+import (
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
+// RootDirs extends the RootDirMap of the build and launch envs, e.g. to prepend the lib/python3/site-packages
+// directory of each layer to PYTHONPATH. Directories are slash-separated paths relative to a layer.
+type RootDirs struct {
+	Build  map[string][]string `toml:"build"`
+	Launch map[string][]string `toml:"launch"`
+}
+
+// ReadRootDirs reads and validates RootDirs from a TOML file.
+func ReadRootDirs(path string) (RootDirs, error) {
+	var rootDirs RootDirs
+	if _, err := toml.DecodeFile(path, &rootDirs); err != nil {
+		return RootDirs{}, errors.Wrap(err, "read root dirs")
+	}
+	if err := validateRootDirMap(rootDirs.Build); err != nil {
+		return RootDirs{}, errors.Wrap(err, "build root dirs")
+	}
+	if err := validateRootDirMap(rootDirs.Launch); err != nil {
+		return RootDirs{}, errors.Wrap(err, "launch root dirs")
+	}
+	return rootDirs, nil
+}
+
+func validateRootDirMap(m map[string][]string) error {
+	for dir, vars := range m {
+		if dir == "" || path.IsAbs(dir) || path.Clean(dir) != dir || dir == ".." || strings.HasPrefix(dir, "../") {
+			return errors.Errorf("directory '%s' must be a clean path within the layer", dir)
+		}
+		for _, name := range vars {
+			if name == "" || strings.ContainsAny(name, "=\x00") {
+				return errors.Errorf("directory '%s' has invalid variable name '%s'", dir, name)
+			}
+		}
+	}
+	return nil
+}
+
+// ExtendRootDirMap returns a new RootDirMap with the directories and variables of ext added to those of base.
+func ExtendRootDirMap(base, ext map[string][]string) map[string][]string {
+	out := map[string][]string{}
+	for _, m := range []map[string][]string{base, ext} {
+		for dir, vars := range m {
+			for _, name := range vars {
+				if !contains(out[dir], name) {
+					out[dir] = append(out[dir], name)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package env_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/env"
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestRootDirs(t *testing.T) {
+	spec.Run(t, "RootDirs", testRootDirs, spec.Report(report.Terminal{}))
+}
+
+func testRootDirs(t *testing.T, when spec.G, it spec.S) {
+	var tmpDir string
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "lifecycle.root-dirs.")
+		h.AssertNil(t, err)
+	})
+
+	it.After(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	when("#ReadRootDirs", func() {
+		it("should read the build and launch root dirs", func() {
+			path := filepath.Join(tmpDir, "root-dirs.toml")
+			h.Mkfile(t, `
+[build]
+"lib/python3/site-packages" = ["PYTHONPATH"]
+
+[launch]
+"lib/python3/site-packages" = ["PYTHONPATH"]
+"share/man" = ["MANPATH"]
+`, path)
+
+			rootDirs, err := env.ReadRootDirs(path)
+			h.AssertNil(t, err)
+			h.AssertEq(t, rootDirs, env.RootDirs{
+				Build: map[string][]string{"lib/python3/site-packages": {"PYTHONPATH"}},
+				Launch: map[string][]string{
+					"lib/python3/site-packages": {"PYTHONPATH"},
+					"share/man":                 {"MANPATH"},
+				},
+			})
+		})
+
+		it("should fail for a directory outside the layer", func() {
+			for _, dir := range []string{"/usr/lib", "../lib", "lib/../../lib", "lib/", ""} {
+				path := filepath.Join(tmpDir, "root-dirs.toml")
+				h.Mkfile(t, "[launch]\n\""+dir+"\" = [\"SOME_VAR\"]\n", path)
+
+				_, err := env.ReadRootDirs(path)
+				h.AssertError(t, err, "launch root dirs: directory '"+dir+"' must be a clean path within the layer")
+			}
+		})
+
+		it("should fail for an invalid variable name", func() {
+			path := filepath.Join(tmpDir, "root-dirs.toml")
+			h.Mkfile(t, "[build]\nlib = [\"A=B\"]\n", path)
+
+			_, err := env.ReadRootDirs(path)
+			h.AssertError(t, err, "build root dirs: directory 'lib' has invalid variable name 'A=B'")
+		})
+	})
+
+	when("#ExtendRootDirMap", func() {
+		it("should add the directories and variables without modifying the base", func() {
+			out := env.ExtendRootDirMap(env.POSIXLaunchEnv, map[string][]string{
+				"lib":       {"LD_LIBRARY_PATH", "LIBRARY_PATH"},
+				"share/man": {"MANPATH"},
+			})
+
+			h.AssertEq(t, out, map[string][]string{
+				"bin":       {"PATH"},
+				"lib":       {"LD_LIBRARY_PATH", "LIBRARY_PATH"},
+				"share/man": {"MANPATH"},
+			})
+			h.AssertEq(t, env.POSIXLaunchEnv, map[string][]string{
+				"bin": {"PATH"},
+				"lib": {"LD_LIBRARY_PATH"},
+			})
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Actions recorded in a Trace in addition to the ActionType of an env file.
+const (
+	TraceActionInitial     = "initial"      // the value the variable had before it was first modified
+	TraceActionRootDir     = "root-dir"     // a layer directory in the RootDirMap was prepended by AddRootDir
+	TraceActionPlatform    = "platform"     // a platform env file was applied by WithPlatform
+	TraceActionSet         = "set"          // the variable was set by Set, e.g. from the output of an exec.d executable
+	TraceActionPrependPath = "prepend-path" // an env file was applied with ActionTypePrependPath
+)
+
+// Modification is a change made to an environment variable.
+type Modification struct {
+	Action    string `toml:"action"`
+	Source    string `toml:"source,omitempty"` // the file or directory the change came from
+	Layer     string `toml:"layer,omitempty"`
+	Buildpack string `toml:"buildpack,omitempty"`
+	Value     string `toml:"value"`             // the value of the variable after the change
+	Skipped   bool   `toml:"skipped,omitempty"` // a default that was not applied because the variable was already set
+}
+
+// VarTrace is the ordered list of modifications made to a variable.
+type VarTrace struct {
+	Name          string         `toml:"name"`
+	Modifications []Modification `toml:"modifications"`
+}
+
+// Trace records the modifications made to the variables of an Env, in order.
+type Trace struct {
+	// Buildpack is recorded with each modification other than those from the platform; callers set it to the
+	// buildpack whose layers are being applied.
+	Buildpack string
+	// Layer and Source are recorded with modifications made by Set, which do not come from an env file.
+	Layer  string
+	Source string
+
+	vars map[string][]Modification
+}
+
+// set sets the variable in vars, recording the modification if t is not nil.
+func (t *Trace) set(vars *Vars, name, value string, mod Modification) {
+	if t != nil {
+		mod.Value = value
+		t.record(vars.key(name), vars.Get(name), mod)
+	}
+	vars.Set(name, value)
+}
+
+// skip records a modification that was not applied to the variable in vars.
+func (t *Trace) skip(vars *Vars, name string, mod Modification) {
+	if t == nil {
+		return
+	}
+	mod.Value = vars.Get(name)
+	mod.Skipped = true
+	t.record(vars.key(name), mod.Value, mod)
+}
+
+// record appends the modification, preceded by the value before it if this is the first modification of a variable
+// that was already set. A modification identical to the previous one is recorded once, as WithPlatform applies the
+// same platform env files each time it is called.
+func (t *Trace) record(key, before string, mod Modification) {
+	if t.vars == nil {
+		t.vars = map[string][]Modification{}
+	}
+	mods := t.vars[key]
+	if len(mods) == 0 && before != "" {
+		mods = append(mods, Modification{Action: TraceActionInitial, Value: before})
+	}
+	if len(mods) > 0 && mods[len(mods)-1] == mod {
+		return
+	}
+	t.vars[key] = append(mods, mod)
+}
+
+// Vars returns the modifications made to each variable, sorted by variable name.
+func (t *Trace) Vars() []VarTrace {
+	var out []VarTrace
+	for name, mods := range t.vars {
+		out = append(out, VarTrace{Name: name, Modifications: append([]Modification(nil), mods...)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Explain writes each modified variable with its value in environ, the environment that was executed with, followed
+// by the modifications that produced it.
+func (t *Trace) Explain(w io.Writer, environ []string) error {
+	vars := varsFromEnv(environ, ignoreEnvVarCase, func(string) bool { return false })
+	for _, v := range t.Vars() {
+		line := v.Name + " is not set\n"
+		if final, ok := vars.vals[vars.key(v.Name)]; ok {
+			line = v.Name + "=" + final + "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+		for _, mod := range v.Modifications {
+			if _, err := fmt.Fprintf(w, "  %s\n", mod); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m Modification) String() string {
+	var origin []string
+	if m.Buildpack != "" {
+		origin = append(origin, "buildpack '"+m.Buildpack+"'")
+	}
+	if m.Layer != "" {
+		origin = append(origin, "layer '"+m.Layer+"'")
+	}
+	if m.Source != "" {
+		origin = append(origin, "from "+m.Source)
+	}
+	s := fmt.Sprintf("%-12s %q", m.Action, m.Value)
+	if m.Skipped {
+		s = fmt.Sprintf("%-12s skipped, already set", m.Action)
+	}
+	if len(origin) > 0 {
+		s += " (" + strings.Join(origin, ", ") + ")"
+	}
+	return s
+}
+
+// envDirLayer returns the name of the layer containing an env dir: <layer>/env, <layer>/env.build,
+// <layer>/env.launch or <layer>/env.launch/<process-type>.
+func envDirLayer(envDir string) string {
+	dir := filepath.Dir(envDir)
+	if filepath.Base(dir) == "env.launch" {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Base(dir)
+}
@@ -26,8 +26,9 @@ func ProcessPath(pType string) string {
 }
 
 type Metadata struct {
-	Processes  []Process   `toml:"processes" json:"processes"`
-	Buildpacks []Buildpack `toml:"buildpacks" json:"buildpacks"`
+	Processes  []Process           `toml:"processes" json:"processes"`
+	Buildpacks []Buildpack         `toml:"buildpacks" json:"buildpacks"`
+	RootDirs   map[string][]string `toml:"root-dirs,omitempty" json:"root-dirs,omitempty"` // extends env.POSIXLaunchEnv
 }
 
 func (m Metadata) FindProcessType(pType string) (Process, bool) {
@@ -1,6 +1,7 @@
 package launch
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -30,6 +31,7 @@ type Launcher struct {
 	PlatformAPI        *api.Version
 	Processes          []Process
 	Setenv             func(string, string) error
+	Trace              *env.Trace // when set, the buildpack and exec.d file making each env modification are recorded
 }
 
 type ExecFunc func(argv0 string, argv []string, envv []string) error
@@ -75,6 +77,30 @@ func (l *Launcher) LaunchProcess(self string, proc Process) error {
 	return l.launchWithShell(self, proc)
 }
 
+// ExplainEnv modifies the env for the process selected by cmd as LaunchProcess would, including running exec.d, and
+// then writes the value each modified variable would be launched with, and how it got that value, to w instead of
+// launching the process.
+// The Env must record its modifications in the Launcher Trace.
+func (l *Launcher) ExplainEnv(cmd []string, w io.Writer) error {
+	if l.Trace == nil {
+		return errors.New("env trace is not configured")
+	}
+	proc, err := l.ProcessFor(cmd)
+	if err != nil {
+		return errors.Wrap(err, "determine start command")
+	}
+	if err := os.Chdir(l.AppDir); err != nil {
+		return errors.Wrap(err, "change to app directory")
+	}
+	if err := l.doEnv(proc.Type); err != nil {
+		return errors.Wrap(err, "modify env")
+	}
+	if err := l.doExecD(proc.Type); err != nil {
+		return errors.Wrap(err, "exec.d")
+	}
+	return l.Trace.Explain(w, l.Env.List())
+}
+
 func (l *Launcher) launchDirect(proc Process) error {
 	if err := l.Setenv("PATH", l.Env.Get("PATH")); err != nil {
 		return errors.Wrap(err, "set path")
@@ -133,6 +159,9 @@ func (l *Launcher) eachBuildpack(fn bpAction) error {
 		if err != nil {
 			return err
 		}
+		if l.Trace != nil {
+			l.Trace.Buildpack = bp.ID
+		}
 		if err := fn(bpAPI, dir); err != nil {
 			return err
 		}
@@ -162,18 +191,24 @@ func (l *Launcher) doLayerEnvFiles(procType string, defaultAction env.ActionType
 }
 
 func (l *Launcher) doLayerExecD(procType string) dirAction {
-	return func(path string) error {
-		if err := eachFile(filepath.Join(path, "exec.d"), func(path string) error {
-			return l.ExecD.ExecD(path, l.Env)
-		}); err != nil {
+	return func(layerDir string) error {
+		if err := eachFile(filepath.Join(layerDir, "exec.d"), l.execD(layerDir)); err != nil {
 			return err
 		}
 		if procType == "" {
 			return nil
 		}
-		return eachFile(filepath.Join(path, "exec.d", procType), func(path string) error {
-			return l.ExecD.ExecD(path, l.Env)
-		})
+		return eachFile(filepath.Join(layerDir, "exec.d", procType), l.execD(layerDir))
+	}
+}
+
+func (l *Launcher) execD(layerDir string) dirAction {
+	return func(path string) error {
+		if l.Trace != nil {
+			l.Trace.Layer = filepath.Base(layerDir)
+			l.Trace.Source = path
+		}
+		return l.ExecD.ExecD(path, l.Env)
 	}
 }
 
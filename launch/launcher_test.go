@@ -15,6 +15,7 @@ import (
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
+	"github.com/buildpacks/lifecycle/api"
 	"github.com/buildpacks/lifecycle/env"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/launch/testmock"
@@ -678,6 +679,57 @@ func testLauncher(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 	})
+	when("ExplainEnv", func() {
+		it.Before(func() {
+			launcher.PlatformAPI = api.MustParse("0.6")
+			launcher.Processes = []launch.Process{{Type: "web", Command: "web-cmd"}}
+			launcher.Trace = &env.Trace{}
+			launcher.Env = &env.Env{
+				RootDirMap: env.POSIXLaunchEnv,
+				Vars:       env.NewVars(map[string]string{"SOME_VAR": "orig"}, false),
+				Trace:      launcher.Trace,
+			}
+			mkdir(t,
+				filepath.Join(tmpDir, "launch", "0.5_buildpack", "layer5", "env.launch", "web"),
+				filepath.Join(tmpDir, "launch", "0.5_buildpack", "layer5", "exec.d"),
+			)
+			mkfile(t, "launch-val", filepath.Join(tmpDir, "launch", "0.5_buildpack", "layer5", "env.launch", "web", "SOME_VAR.override"))
+			mkfile(t, "", filepath.Join(tmpDir, "launch", "0.5_buildpack", "layer5", "exec.d", "exec_d_1"))
+			launcher.ExecD = &fakeExecD{vars: map[string]string{"EXECD_VAR": "execd-val"}}
+		})
+
+		it("should explain the env of the process without launching it", func() {
+			out := &strings.Builder{}
+			h.AssertNil(t, launcher.ExplainEnv(nil, out))
+			if len(syscallExecArgsColl) != 0 {
+				t.Fatalf("expected syscall.Exec not to be called: actual %v\n", syscallExecArgsColl)
+			}
+
+			layerDir := filepath.Join(tmpDir, "launch", "0.5_buildpack", "layer5")
+			expected := "EXECD_VAR=execd-val\n" +
+				"  set          \"execd-val\" (buildpack '0.5/buildpack', layer 'layer5', from " + filepath.Join(layerDir, "exec.d", "exec_d_1") + ")\n" +
+				"SOME_VAR=launch-val\n" +
+				"  initial      \"orig\"\n" +
+				"  override     \"launch-val\" (buildpack '0.5/buildpack', layer 'layer5', from " + filepath.Join(layerDir, "env.launch", "web", "SOME_VAR.override") + ")\n"
+			h.AssertEq(t, out.String(), expected)
+		})
+
+		it("should fail if the env is not traced", func() {
+			launcher.Trace = nil
+			h.AssertError(t, launcher.ExplainEnv(nil, &strings.Builder{}), "env trace is not configured")
+		})
+	})
+}
+
+type fakeExecD struct {
+	vars map[string]string
+}
+
+func (f *fakeExecD) ExecD(_ string, env launch.Env) error {
+	for k, v := range f.vars {
+		env.Set(k, v)
+	}
+	return nil
 }
 
 func mkfile(t *testing.T, data string, paths ...string) {
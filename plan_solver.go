@@ -0,0 +1,130 @@
+package lifecycle
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type pruneFunc func(prefix detectTrial, reason, name string)
+
+// planSolver searches the combinations of plan alternatives in a group for the first one, in order, that resolves.
+//
+// Combinations are visited in the same order as an exhaustive search, so the first match is unchanged, but branches
+// that cannot resolve are pruned before any trial is run. A required option can never resolve if one of its requires
+// has no provider at or before it, or if one of its provides is not required by any later buildpack. Both checks
+// depend only on the position in the group and the names provided so far, so states from which every combination
+// was pruned are memoized and skipped when they recur.
+type planSolver struct {
+	results   detectResults
+	trial     trialFunc
+	prune     pruneFunc
+	consumers []map[string]bool // names required by any alternative of results[i+1:]
+	deadEnds  map[string]bool
+}
+
+func newPlanSolver(results detectResults, trial trialFunc, prune pruneFunc) *planSolver {
+	consumers := make([]map[string]bool, len(results))
+	later := map[string]bool{}
+	for i := len(results) - 1; i >= 0; i-- {
+		consumers[i] = later
+		next := map[string]bool{}
+		for name := range later {
+			next[name] = true
+		}
+		for _, option := range results[i].options() {
+			for _, r := range option.Requires {
+				next[r.Name] = true
+			}
+		}
+		later = next
+	}
+	return &planSolver{
+		results:   results,
+		trial:     trial,
+		prune:     prune,
+		consumers: consumers,
+		deadEnds:  map[string]bool{},
+	}
+}
+
+func (s *planSolver) solve() (depMap, detectTrial, error) {
+	deps, trial, _, err := s.solveFrom(nil, map[string]bool{})
+	return deps, trial, err
+}
+
+// solveFrom returns the first resolved trial that extends prefix, where provided holds the names provided by prefix.
+// It also reports whether any trial was run, so that states without viable combinations can be memoized.
+func (s *planSolver) solveFrom(prefix detectTrial, provided map[string]bool) (depMap, detectTrial, bool, error) {
+	if len(prefix) == len(s.results) {
+		deps, trial, err := s.trial(prefix)
+		return deps, trial, true, err
+	}
+
+	key := stateKey(len(prefix), provided)
+	if s.deadEnds[key] {
+		return nil, nil, false, ErrFailedDetection
+	}
+
+	var lastErr error = ErrFailedDetection
+	tried := false
+	for _, option := range s.results[len(prefix)].options() {
+		next := append(prefix, option)
+		available := provided
+		if len(option.Provides) > 0 {
+			available = map[string]bool{}
+			for name := range provided {
+				available[name] = true
+			}
+			for _, p := range option.Provides {
+				available[p.Name] = true
+			}
+		}
+		if reason, name, ok := s.viable(len(prefix), option, available); !ok {
+			s.prune(next, reason, name)
+			continue
+		}
+
+		deps, trial, ran, err := s.solveFrom(next, available)
+		tried = tried || ran
+		if err == nil {
+			return deps, trial, true, nil
+		}
+		lastErr = err
+	}
+	if !tried {
+		s.deadEnds[key] = true
+	}
+	return nil, nil, tried, lastErr
+}
+
+// viable checks whether the option could take part in a resolved trial. Optional options are always viable,
+// because an unmet require or provide removes them from the trial instead of failing it.
+func (s *planSolver) viable(i int, option detectOption, available map[string]bool) (reason, name string, ok bool) {
+	if option.Optional {
+		return "", "", true
+	}
+	requires := map[string]bool{}
+	for _, r := range option.Requires {
+		if !available[r.Name] {
+			return "requires", r.Name, false
+		}
+		requires[r.Name] = true
+	}
+	consumers := s.consumers[i]
+	for _, p := range option.Provides {
+		if !requires[p.Name] && !consumers[p.Name] {
+			return "provides-unused", p.Name, false
+		}
+	}
+	return "", "", true
+}
+
+func stateKey(i int, provided map[string]bool) string {
+	names := make([]string, 0, len(provided))
+	for name := range provided {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strconv.Itoa(i) + "\x00" + strings.Join(names, "\x00")
+}
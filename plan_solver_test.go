@@ -0,0 +1,161 @@
+package lifecycle
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpacks/lifecycle/buildpack"
+	"github.com/buildpacks/lifecycle/platform"
+)
+
+func TestPlanSolver(t *testing.T) {
+	spec.Run(t, "PlanSolver", testPlanSolver, spec.Report(report.Terminal{}))
+}
+
+func testPlanSolver(t *testing.T, when spec.G, it spec.S) {
+	var resolver *DefaultResolver
+
+	it.Before(func() {
+		resolver = &DefaultResolver{Logger: &log.Logger{Handler: memory.New()}}
+	})
+
+	runTrial := func(trial detectTrial) (depMap, detectTrial, error) {
+		return resolver.runTrial(0, trial, &platform.DetectTrialReport{})
+	}
+
+	when("compared to an exhaustive search", func() {
+		it("selects the same trial and plan for random groups", func() {
+			rng := rand.New(rand.NewSource(1))
+			for n := 0; n < 2000; n++ {
+				results := randomResults(rng)
+
+				var exhaustiveRuns, solverRuns int
+				expDeps, expTrial, expErr := exhaustiveTrials(results, nil, func(trial detectTrial) (depMap, detectTrial, error) {
+					exhaustiveRuns++
+					return runTrial(trial)
+				})
+				deps, trial, err := results.runTrials(func(trial detectTrial) (depMap, detectTrial, error) {
+					solverRuns++
+					return runTrial(trial)
+				}, func(detectTrial, string, string) {})
+
+				if err != expErr {
+					t.Fatalf("Case %d: expected error %v, got %v\n%+v", n, expErr, err, results)
+				}
+				if !reflect.DeepEqual(trial, expTrial) {
+					t.Fatalf("Case %d: expected trial %+v, got %+v\n%+v", n, expTrial, trial, results)
+				}
+				if !reflect.DeepEqual(deps, expDeps) {
+					t.Fatalf("Case %d: expected plan %+v, got %+v\n%+v", n, expDeps, deps, results)
+				}
+				if solverRuns > exhaustiveRuns {
+					t.Fatalf("Case %d: expected at most %d trials, got %d", n, exhaustiveRuns, solverRuns)
+				}
+			}
+		})
+	})
+
+	when("no combination can resolve", func() {
+		it("does not run any trials", func() {
+			results := detectResults{
+				{
+					GroupBuildpack: buildpack.GroupBuildpack{ID: "A", Version: "v1"},
+					DetectRun: buildpack.DetectRun{BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{Provides: []buildpack.Provide{{Name: "dep1"}}},
+						Or:           []buildpack.PlanSections{{Provides: []buildpack.Provide{{Name: "dep2"}}}},
+					}},
+				},
+				{
+					GroupBuildpack: buildpack.GroupBuildpack{ID: "B", Version: "v1"},
+					DetectRun: buildpack.DetectRun{BuildPlan: buildpack.BuildPlan{
+						PlanSections: buildpack.PlanSections{Requires: []buildpack.Require{{Name: "dep3"}}},
+					}},
+				},
+			}
+
+			var pruned []string
+			_, _, err := results.runTrials(func(trial detectTrial) (depMap, detectTrial, error) {
+				t.Fatalf("Unexpected trial: %+v", trial)
+				return nil, nil, nil
+			}, func(prefix detectTrial, reason, name string) {
+				pruned = append(pruned, fmt.Sprintf("%d:%s:%s", len(prefix), reason, name))
+			})
+			if err != ErrFailedDetection {
+				t.Fatalf("Expected ErrFailedDetection, got %v", err)
+			}
+			if !reflect.DeepEqual(pruned, []string{
+				"1:provides-unused:dep1",
+				"1:provides-unused:dep2",
+			}) {
+				t.Fatalf("Unexpected pruned branches: %v", pruned)
+			}
+		})
+	})
+}
+
+// exhaustiveTrials is the reference implementation: it tries every combination of plan alternatives in order.
+func exhaustiveTrials(rs detectResults, prefix detectTrial, f trialFunc) (depMap, detectTrial, error) {
+	if len(rs) == 0 {
+		return f(prefix)
+	}
+	var lastErr error
+	for _, option := range rs[0].options() {
+		deps, trial, err := exhaustiveTrials(rs[1:], append(prefix, option), f)
+		if err == nil {
+			return deps, trial, nil
+		}
+		lastErr = err
+	}
+	return nil, nil, lastErr
+}
+
+func randomResults(rng *rand.Rand) detectResults {
+	names := []string{"dep1", "dep2", "dep3", "dep4"}
+	versions := []string{"", "1.0.0", "2.0.0"}
+	constraints := []string{"", "^1", ">=2"}
+	randomProvide := func(name string) buildpack.Provide {
+		return buildpack.Provide{Name: name, Version: versions[rng.Intn(len(versions))]}
+	}
+	randomRequire := func(name string) buildpack.Require {
+		return buildpack.Require{Name: name, Version: constraints[rng.Intn(len(constraints))]}
+	}
+	randomSections := func() buildpack.PlanSections {
+		var sections buildpack.PlanSections
+		for _, name := range names {
+			switch rng.Intn(6) {
+			case 0:
+				sections.Provides = append(sections.Provides, randomProvide(name))
+			case 1:
+				sections.Requires = append(sections.Requires, randomRequire(name))
+			case 2:
+				sections.Provides = append(sections.Provides, randomProvide(name))
+				sections.Requires = append(sections.Requires, randomRequire(name))
+			}
+		}
+		return sections
+	}
+
+	var results detectResults
+	for i := rng.Intn(6); i > 0; i-- {
+		result := detectResult{
+			GroupBuildpack: buildpack.GroupBuildpack{
+				ID:       fmt.Sprintf("bp%d", len(results)),
+				Version:  "v1",
+				Optional: rng.Intn(3) == 0,
+			},
+		}
+		result.PlanSections = randomSections()
+		for j := rng.Intn(4); j > 0; j-- {
+			result.Or = append(result.Or, randomSections())
+		}
+		results = append(results, result)
+	}
+	return results
+}
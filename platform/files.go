@@ -8,6 +8,7 @@ import (
 
 	"github.com/buildpacks/lifecycle/buildpack"
 	"github.com/buildpacks/lifecycle/buildpack/layertypes"
+	"github.com/buildpacks/lifecycle/env"
 	"github.com/buildpacks/lifecycle/launch"
 	"github.com/buildpacks/lifecycle/layers"
 )
@@ -89,6 +90,7 @@ type BuildMetadata struct {
 	Processes                   []launch.Process           `toml:"processes" json:"processes"`
 	Slices                      []layers.Slice             `toml:"slices" json:"-"`
 	BuildpackDefaultProcessType string                     `toml:"buildpack-default-process-type,omitempty" json:"buildpack-default-process-type,omitempty"`
+	RootDirs                    map[string][]string        `toml:"root-dirs,omitempty" json:"-"` // extends the RootDirMap of the launch env
 }
 
 type LauncherMetadata struct {
@@ -173,6 +175,64 @@ func (be BuildPlanEntry) NoOpt() BuildPlanEntry {
 	return be
 }
 
+// detect-report.toml
+
+type DetectReport struct {
+	Groups []DetectGroupReport `toml:"groups" json:"groups"`
+}
+
+// DetectGroupReport describes a single attempt to resolve a group of buildpacks from the order.
+type DetectGroupReport struct {
+	Result     string                  `toml:"result" json:"result"`
+	Buildpacks []DetectBuildpackReport `toml:"buildpacks" json:"buildpacks"`
+	Trials     []DetectTrialReport     `toml:"trials" json:"trials,omitempty"`
+	Selected   []DetectOptionReport    `toml:"selected" json:"selected,omitempty"`
+}
+
+type DetectBuildpackReport struct {
+	ID         string `toml:"id" json:"id"`
+	Version    string `toml:"version" json:"version"`
+	Optional   bool   `toml:"optional,omitempty" json:"optional,omitempty"`
+	Result     string `toml:"result" json:"result"`
+	ExitCode   int    `toml:"exit-code" json:"exitCode"`
+	DurationMS int64  `toml:"duration-ms" json:"durationMs"`
+	Output     string `toml:"output,omitempty" json:"output,omitempty"`
+	Error      string `toml:"error,omitempty" json:"error,omitempty"`
+	Source     string `toml:"source,omitempty" json:"source,omitempty"`
+	Reason     string `toml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// DetectTrialReport describes an attempt to resolve a build plan from one combination of buildpack plan alternatives.
+// A pruned trial lists only the leading buildpacks whose alternatives ruled out every combination that starts with them.
+type DetectTrialReport struct {
+	Options    []DetectOptionReport    `toml:"options" json:"options"`
+	Rejections []DetectRejectionReport `toml:"rejections" json:"rejections,omitempty"`
+	Result     string                  `toml:"result" json:"result"`
+	Pruned     bool                    `toml:"pruned,omitempty" json:"pruned,omitempty"`
+}
+
+// DetectOptionReport identifies the plan alternative used for a buildpack.
+// Alternative 0 is the top-level plan and alternative N is the Nth [[or]] section.
+type DetectOptionReport struct {
+	ID          string `toml:"id" json:"id"`
+	Version     string `toml:"version" json:"version"`
+	Alternative int    `toml:"alternative" json:"alternative"`
+}
+
+// DetectRejectionReport records an unmet require or provide that removed a buildpack from a trial ("skip")
+// or caused the trial to be rejected ("fail").
+// For a "version-conflict", it also records the require's constraint and the provider and version that failed it.
+type DetectRejectionReport struct {
+	ID         string `toml:"id,omitempty" json:"id,omitempty"`
+	Version    string `toml:"version,omitempty" json:"version,omitempty"`
+	Action     string `toml:"action" json:"action"`
+	Reason     string `toml:"reason" json:"reason"`
+	Name       string `toml:"name,omitempty" json:"name,omitempty"`
+	Constraint string `toml:"constraint,omitempty" json:"constraint,omitempty"`
+	Provider   string `toml:"provider,omitempty" json:"provider,omitempty"`
+	Provided   string `toml:"provided,omitempty" json:"provided,omitempty"`
+}
+
 // project-metadata.toml
 
 type ProjectMetadata struct {
@@ -193,7 +253,48 @@ type ExportReport struct {
 }
 
 type BuildReport struct {
-	BOM []buildpack.BOMEntry `toml:"bom"`
+	BOM        []buildpack.BOMEntry `toml:"bom"`
+	Buildpacks []BuildpackStats     `toml:"buildpacks,omitempty"`
+	Offline    bool                 `toml:"offline,omitempty"`
+}
+
+// build-report.toml
+
+// BuildStatsReport records the time and resources used by each buildpack's bin/build, in group order.
+type BuildStatsReport struct {
+	Buildpacks []BuildpackStats `toml:"buildpacks"`
+	Offline    bool             `toml:"offline,omitempty"` // the buildpacks ran without network access
+}
+
+type BuildpackStats struct {
+	ID          string       `toml:"id"`
+	Version     string       `toml:"version"`
+	DurationMS  int64        `toml:"duration-ms"`
+	UserCPUMS   int64        `toml:"user-cpu-ms"`
+	SystemCPUMS int64        `toml:"system-cpu-ms"`
+	MaxRSSBytes int64        `toml:"max-rss-bytes,omitzero"`
+	Layers      []LayerStats `toml:"layers,omitempty"`
+	Error       string       `toml:"error,omitempty"`
+	Resumed     bool         `toml:"resumed,omitempty"` // bin/build ran before the checkpoint the build resumed from
+}
+
+// LayerStats is the size on disk of a layer created by a buildpack.
+type LayerStats struct {
+	Name      string `toml:"name"`
+	SizeBytes int64  `toml:"size-bytes"`
+}
+
+// BuildEnvReport records how each variable of each buildpack's build env got its value, in group order.
+// Only the modifications made before bin/build ran are included, so that the last value of each variable is the value
+// bin/build ran with.
+type BuildEnvReport struct {
+	Buildpacks []BuildpackEnv `toml:"buildpacks"`
+}
+
+type BuildpackEnv struct {
+	ID      string         `toml:"id"`
+	Version string         `toml:"version"`
+	Vars    []env.VarTrace `toml:"vars,omitempty"`
 }
 
 type ImageReport struct {
@@ -9,6 +9,7 @@ var exitCodes = map[cmd.LifecycleExitError]int{
 	cmd.FailedDetect:           100, // FailedDetect indicates that no buildpacks detected
 	cmd.FailedDetectWithErrors: 101, // FailedDetectWithErrors indicated that no buildpacks detected and at least one errored
 	cmd.DetectError:            102, // DetectError indicates generic detect error
+	cmd.DetectInterrupted:      103, // DetectInterrupted indicates the detect phase was interrupted by a signal
 
 	// analyze phase errors: 200-299
 	cmd.AnalyzeError: 202, // AnalyzeError indicates generic analyze error
@@ -19,6 +20,7 @@ var exitCodes = map[cmd.LifecycleExitError]int{
 	// build phase errors: 400-499
 	cmd.FailedBuildWithErrors: 401, // FailedBuildWithErrors indicates buildpack error during /bin/build
 	cmd.BuildError:            402, // BuildError indicates generic build error
+	cmd.BuildInterrupted:      403, // BuildInterrupted indicates the build phase was interrupted by a signal
 
 	// export phase errors: 500-599
 	cmd.ExportError: 502, // ExportError indicates generic export error
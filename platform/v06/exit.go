@@ -9,6 +9,7 @@ var exitCodes = map[cmd.LifecycleExitError]int{
 	cmd.FailedDetect:           20, // FailedDetect indicates that no buildpacks detected
 	cmd.FailedDetectWithErrors: 21, // FailedDetectWithErrors indicated that no buildpacks detected and at least one errored
 	cmd.DetectError:            22, // DetectError indicates generic detect error
+	cmd.DetectInterrupted:      23, // DetectInterrupted indicates the detect phase was interrupted by a signal
 
 	// analyze phase errors: 30-39
 	cmd.AnalyzeError: 32, // AnalyzeError indicates generic analyze error
@@ -19,6 +20,7 @@ var exitCodes = map[cmd.LifecycleExitError]int{
 	// build phase errors: 50-59
 	cmd.FailedBuildWithErrors: 51, // FailedBuildWithErrors indicates buildpack error during /bin/build
 	cmd.BuildError:            52, // BuildError indicates generic build error
+	cmd.BuildInterrupted:      53, // BuildInterrupted indicates the build phase was interrupted by a signal
 
 	// export phase errors: 60-69
 	cmd.ExportError: 62, // ExportError indicates generic export error
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -39,6 +40,37 @@ func ReadOrder(path string) (buildpack.Order, error) {
 	return order.Order, err
 }
 
+// SelectGroup returns an order containing only the group identified by selector: either a 1-based index into the
+// order, or the ID (optionally with "@<version>") of the buildpack at the head of the group.
+func SelectGroup(order buildpack.Order, selector string) (buildpack.Order, error) {
+	if i, err := strconv.Atoi(selector); err == nil {
+		if i < 1 || i > len(order) {
+			return nil, errors.Errorf("group %d is out of range: the order has %d group(s)", i, len(order))
+		}
+		return buildpack.Order{order[i-1]}, nil
+	}
+	var matches []int
+	for i, group := range order {
+		if len(group.Group) == 0 {
+			continue
+		}
+		if head := group.Group[0]; head.ID == selector || head.String() == selector {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, errors.Errorf("no group in the order starts with buildpack '%s'", selector)
+	case 1:
+		return buildpack.Order{order[matches[0]]}, nil
+	}
+	var indexes []string
+	for _, i := range matches {
+		indexes = append(indexes, strconv.Itoa(i+1))
+	}
+	return nil, errors.Errorf("groups %s all start with buildpack '%s'; select one by its index", strings.Join(indexes, ", "), selector)
+}
+
 func TruncateSha(sha string) string {
 	rawSha := strings.TrimPrefix(sha, "sha256:")
 	if len(sha) > 12 {
@@ -56,6 +56,41 @@ func testUtils(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when(".SelectGroup", func() {
+		order := buildpack.Order{
+			{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v1"}, {ID: "B", Version: "v1"}}},
+			{Group: []buildpack.GroupBuildpack{{ID: "C", Version: "v1"}}},
+			{Group: []buildpack.GroupBuildpack{{ID: "A", Version: "v2"}}},
+		}
+
+		it("should select a group by its 1-based index", func() {
+			selected, err := lifecycle.SelectGroup(order, "2")
+			h.AssertNil(t, err)
+			h.AssertEq(t, selected, buildpack.Order{order[1]})
+		})
+
+		it("should select a group by the buildpack at its head", func() {
+			selected, err := lifecycle.SelectGroup(order, "C")
+			h.AssertNil(t, err)
+			h.AssertEq(t, selected, buildpack.Order{order[1]})
+
+			selected, err = lifecycle.SelectGroup(order, "A@v2")
+			h.AssertNil(t, err)
+			h.AssertEq(t, selected, buildpack.Order{order[2]})
+		})
+
+		it("should fail when the selection is ambiguous or matches no group", func() {
+			_, err := lifecycle.SelectGroup(order, "A")
+			h.AssertError(t, err, "groups 1, 3 all start with buildpack 'A'; select one by its index")
+
+			_, err = lifecycle.SelectGroup(order, "B")
+			h.AssertError(t, err, "no group in the order starts with buildpack 'B'")
+
+			_, err = lifecycle.SelectGroup(order, "4")
+			h.AssertError(t, err, "group 4 is out of range: the order has 3 group(s)")
+		})
+	})
+
 	when(".ReadGroup", func() {
 		var tmpDir string
 
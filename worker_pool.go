@@ -0,0 +1,90 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// workerPool limits the number of detect runs in progress, giving runs for the group being detected priority over
+// speculative runs.
+type workerPool struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	size       int
+	running    int
+	foreground int // runs for the group being detected that are waiting for a worker
+}
+
+// speculativeRun is the state of a speculative run in a workerPool, guarded by its mutex.
+type speculativeRun struct {
+	promoted bool // the group being detected is waiting for the run
+	acquired bool
+}
+
+func newWorkerPool(size int) *workerPool {
+	p := &workerPool{size: size}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// acquire waits for a worker for a run, which is nil for a run for the group being detected. A speculative run also
+// waits while any such run is waiting, unless it has been promoted. It returns false without a worker if ctx, which
+// may be nil, is done first.
+func (p *workerPool) acquire(ctx context.Context, run *speculativeRun) bool {
+	if ctx != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				p.mu.Lock()
+				p.cond.Broadcast()
+				p.mu.Unlock()
+			case <-stop:
+			}
+		}()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if run == nil {
+		p.foreground++
+	}
+	for p.running >= p.size || (run != nil && p.foreground > 0 && !run.promoted) {
+		if ctx != nil && ctx.Err() != nil {
+			if run == nil || run.promoted {
+				p.foreground--
+				p.cond.Broadcast()
+			}
+			return false
+		}
+		p.cond.Wait()
+	}
+	if run == nil || run.promoted {
+		p.foreground--
+	}
+	if run != nil {
+		run.acquired = true
+	}
+	p.running++
+	return true
+}
+
+// promote gives a speculative run that has not yet acquired a worker the priority of a run for the group being
+// detected.
+func (p *workerPool) promote(run *speculativeRun) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if run.promoted || run.acquired {
+		return
+	}
+	run.promoted = true
+	p.foreground++
+	p.cond.Broadcast()
+}
+
+func (p *workerPool) release() {
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
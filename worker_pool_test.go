@@ -0,0 +1,156 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	h "github.com/buildpacks/lifecycle/testhelpers"
+)
+
+func TestWorkerPool(t *testing.T) {
+	spec.Run(t, "WorkerPool", testWorkerPool, spec.Report(report.Terminal{}))
+}
+
+func testWorkerPool(t *testing.T, when spec.G, it spec.S) {
+	var pool *workerPool
+
+	it.Before(func() {
+		pool = newWorkerPool(1)
+	})
+
+	// acquire acquires a worker in the background and returns a channel that is closed once it has one.
+	acquire := func(run *speculativeRun) <-chan struct{} {
+		acquired := make(chan struct{})
+		go func() {
+			pool.acquire(nil, run)
+			close(acquired)
+		}()
+		return acquired
+	}
+
+	waitForForeground := func(n int) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			pool.mu.Lock()
+			waiting := pool.foreground
+			pool.mu.Unlock()
+			if waiting == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("Expected %d foreground runs to be waiting", n)
+	}
+
+	assertWaiting := func(acquired <-chan struct{}) {
+		t.Helper()
+		select {
+		case <-acquired:
+			t.Fatal("Expected run to be waiting for a worker")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	assertAcquired := func(acquired <-chan struct{}) {
+		t.Helper()
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("Expected run to acquire a worker")
+		}
+	}
+
+	it("should give a worker to a waiting foreground run before a speculative run", func() {
+		pool.acquire(nil, &speculativeRun{})
+		specAcquired := acquire(&speculativeRun{})
+		assertWaiting(specAcquired)
+		fgAcquired := acquire(nil)
+		waitForForeground(1)
+
+		pool.release()
+		assertAcquired(fgAcquired)
+		assertWaiting(specAcquired)
+
+		pool.release()
+		assertAcquired(specAcquired)
+		pool.release()
+	})
+
+	it("should give a worker to a promoted speculative run before other speculative runs", func() {
+		pool.acquire(nil, nil)
+		specAcquired := acquire(&speculativeRun{})
+		run := &speculativeRun{}
+		promotedAcquired := acquire(run)
+		assertWaiting(specAcquired)
+		assertWaiting(promotedAcquired)
+
+		pool.promote(run)
+		waitForForeground(1)
+		pool.release()
+		assertAcquired(promotedAcquired)
+		assertWaiting(specAcquired)
+
+		pool.release()
+		assertAcquired(specAcquired)
+		pool.release()
+		h.AssertEq(t, pool.foreground, 0)
+	})
+
+	it("should not promote a run that already has a worker", func() {
+		run := &speculativeRun{}
+		pool.acquire(nil, run)
+		pool.promote(run)
+		h.AssertEq(t, pool.foreground, 0)
+		pool.release()
+	})
+
+	when("the context is cancelled", func() {
+		it("should stop waiting for a worker", func() {
+			pool.acquire(nil, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			result := make(chan bool)
+			go func() {
+				result <- pool.acquire(ctx, &speculativeRun{})
+			}()
+			select {
+			case <-result:
+				t.Fatal("Expected run to be waiting for a worker")
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			cancel()
+			select {
+			case acquired := <-result:
+				h.AssertEq(t, acquired, false)
+			case <-time.After(time.Second):
+				t.Fatal("Expected run to stop waiting")
+			}
+			h.AssertEq(t, pool.running, 1)
+			pool.release()
+		})
+
+		it("should no longer give priority to a promoted run", func() {
+			pool.acquire(nil, nil)
+			specAcquired := acquire(&speculativeRun{})
+			ctx, cancel := context.WithCancel(context.Background())
+			run := &speculativeRun{}
+			result := make(chan bool)
+			go func() {
+				result <- pool.acquire(ctx, run)
+			}()
+			pool.promote(run)
+			waitForForeground(1)
+
+			cancel()
+			h.AssertEq(t, <-result, false)
+			waitForForeground(0)
+			pool.release()
+			assertAcquired(specAcquired)
+			pool.release()
+		})
+	})
+}